@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -10,67 +11,152 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	pw, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+const (
+	accessTokenIssuer    = "chirpy"
+	challengeTokenIssuer = "chirpy-2fa"
+)
 
-	if err != nil {
-		return "", fmt.Errorf("error hashing password: %w", err)
+// Claims are the claims of an access token. SessionID names the refresh
+// token family the access token was issued from, if any.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+}
+
+// MakeJWT, MakeSessionJWT and friends sign and check HS256 tokens with a
+// single shared secret. Servers with asymmetric keys use the KeySet methods
+// of the same name instead.
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewHMACKeySet(tokenSecret).MakeJWT(userID, expiresIn)
+}
+
+func MakeSessionJWT(userID, sessionID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewHMACKeySet(tokenSecret).MakeSessionJWT(userID, sessionID, expiresIn)
+}
+
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return NewHMACKeySet(tokenSecret).ValidateJWT(tokenString)
+}
+
+func ValidateSessionJWT(tokenString, tokenSecret string) (uuid.UUID, uuid.UUID, error) {
+	return NewHMACKeySet(tokenSecret).ValidateSessionJWT(tokenString)
+}
+
+func MakeChallengeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return NewHMACKeySet(tokenSecret).MakeChallengeJWT(userID, expiresIn)
+}
+
+func ValidateChallengeJWT(tokenString, tokenSecret string) (uuid.UUID, uuid.UUID, error) {
+	return NewHMACKeySet(tokenSecret).ValidateChallengeJWT(tokenString)
+}
+
+func (ks *KeySet) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return ks.MakeSessionJWT(userID, uuid.Nil, expiresIn)
+}
+
+func (ks *KeySet) MakeSessionJWT(userID, sessionID uuid.UUID, expiresIn time.Duration) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    accessTokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
 	}
 
-	return string(pw), nil
+	return ks.sign(claims)
 }
 
-func CheckPasswordHash(hash, password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	userID, _, err := ks.ValidateSessionJWT(tokenString)
+	return userID, err
+}
 
+// ValidateSessionJWT is ValidateJWT that also returns the session the token
+// belongs to. The session is uuid.Nil for tokens issued outside a session.
+func (ks *KeySet) ValidateSessionJWT(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	claims, err := ks.parseJWT(tokenString, accessTokenIssuer)
 	if err != nil {
-		return fmt.Errorf("error comparing hash and password: %w", err)
+		return uuid.Nil, uuid.Nil, err
 	}
 
-	return nil
-}
+	// Extract user ID from subject
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid user ID in token: %w", err)
+	}
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
+	sessionID := uuid.Nil
+	if claims.SessionID != "" {
+		sessionID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return uuid.Nil, uuid.Nil, fmt.Errorf("invalid session ID in token: %w", err)
+		}
+	}
 
-	return token.SignedString([]byte(tokenSecret))
+	return userID, sessionID, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
+// MakeChallengeJWT issues the short-lived token a user with 2FA gets after
+// their password checks out. It has its own issuer so it can't be used as
+// an access token. Each one gets its own ID so it can be used up once the
+// login completes.
+func (ks *KeySet) MakeChallengeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    challengeTokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+	}
 
-	// Parse and viladte the token
+	return ks.sign(claims)
+}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(tokenSecret), nil
-	})
+// ValidateChallengeJWT returns the user a challenge token was issued to and
+// the challenge's own ID.
+func (ks *KeySet) ValidateChallengeJWT(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	claims, err := ks.parseJWT(tokenString, challengeTokenIssuer)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
 
+	userID, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("error validating token: %w", err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid user ID in token: %w", err)
 	}
 
-	if !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token: not valid")
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid challenge ID in token: %w", err)
 	}
 
-	// Extract user ID from subject
-	userID, err := uuid.Parse(claims.Subject)
+	return userID, challengeID, nil
+}
+
+func (ks *KeySet) parseJWT(tokenString, issuer string) (*Claims, error) {
+	claims := &Claims{}
+
+	// Parse and viladte the token
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, ks.keyFunc, jwt.WithIssuer(issuer))
+
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID in token: %w", err)
+		return nil, fmt.Errorf("error validating token: %w", err)
 	}
 
-	return userID, nil
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token: not valid")
+	}
+
+	return claims, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -88,6 +174,12 @@ func GetBearerToken(headers http.Header) (string, error) {
 }
 
 func MakeRefreshToken() (string, error) {
+	return MakeToken()
+}
+
+// MakeToken returns 256 random bits, hex encoded. It's used for every opaque
+// token we hand out: refresh tokens, password reset links and so on.
+func MakeToken() (string, error) {
 	data := make([]byte, 32)
 	if _, err := rand.Read(data); err != nil {
 		return "", fmt.Errorf("failed to generate secure token: %w", err)
@@ -95,17 +187,9 @@ func MakeRefreshToken() (string, error) {
 	return hex.EncodeToString(data), nil
 }
 
-func GetAPIKey(headers http.Header) (string, error) {
-	authHeaders := headers["Authorization"]
-	if len(authHeaders) == 0 {
-		return "", fmt.Errorf("no Authorization header found")
-	}
-
-	authHeader := authHeaders[0]
-	if !strings.HasPrefix(authHeader, "ApiKey ") {
-		return "", fmt.Errorf("invalid Authorization header")
-	}
-
-	return strings.TrimPrefix(authHeader, "ApiKey "), nil
-
+// HashToken hashes a token from MakeToken for storage. The tokens are random,
+// so unlike passwords a fast hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
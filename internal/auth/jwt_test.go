@@ -58,3 +58,76 @@ func TestJWTWithWrongSecret(t *testing.T) {
 		t.Errorf("expected error with wrong secret, got none")
 	}
 }
+
+func TestSessionJWT(t *testing.T) {
+	secret := "testsecret"
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	token, err := MakeSessionJWT(userID, sessionID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	parsedUserID, parsedSessionID, err := ValidateSessionJWT(token, secret)
+	if err != nil {
+		t.Fatalf("error validating JWT: %v", err)
+	}
+
+	if parsedUserID != userID || parsedSessionID != sessionID {
+		t.Errorf("expected %v/%v, got %v/%v", userID, sessionID, parsedUserID, parsedSessionID)
+	}
+
+	// tokens made without a session still validate
+	token, err = MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	_, parsedSessionID, err = ValidateSessionJWT(token, secret)
+	if err != nil || parsedSessionID != uuid.Nil {
+		t.Errorf("expected no session, got %v (err %v)", parsedSessionID, err)
+	}
+}
+
+func TestChallengeJWTIsNotAnAccessToken(t *testing.T) {
+	secret := "testsecret"
+	userID := uuid.New()
+
+	challenge, err := MakeChallengeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("error creating challenge JWT: %v", err)
+	}
+
+	if _, err := ValidateJWT(challenge, secret); err == nil {
+		t.Errorf("expected challenge token to be rejected as an access token")
+	}
+
+	parsedUserID, challengeID, err := ValidateChallengeJWT(challenge, secret)
+	if err != nil {
+		t.Fatalf("error validating challenge JWT: %v", err)
+	}
+	if parsedUserID != userID {
+		t.Errorf("expected userID %v, got %v", userID, parsedUserID)
+	}
+
+	other, err := MakeChallengeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("error creating challenge JWT: %v", err)
+	}
+	_, otherID, err := ValidateChallengeJWT(other, secret)
+	if err != nil {
+		t.Fatalf("error validating challenge JWT: %v", err)
+	}
+	if otherID == challengeID {
+		t.Errorf("expected every challenge to get its own ID, got %v twice", challengeID)
+	}
+
+	access, err := MakeJWT(userID, secret, time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+	if _, _, err := ValidateChallengeJWT(access, secret); err == nil {
+		t.Errorf("expected access token to be rejected as a challenge token")
+	}
+}
@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type KeyStatus string
+
+const (
+	// KeyActive keys are published and trusted. The first active key in a
+	// set signs new tokens, the others are there so verifiers already have
+	// them by the time they start signing.
+	KeyActive KeyStatus = "active"
+	// KeyRetiring keys no longer sign but are still published and trusted
+	// until the tokens they signed have expired.
+	KeyRetiring KeyStatus = "retiring"
+)
+
+const minRSAKeyBits = 2048
+
+// Key is one JWT signing key. Verify-only keys have no private half.
+type Key struct {
+	ID     string
+	Status KeyStatus
+
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds every key tokens may be signed with. Tokens carry the ID of
+// their key in the kid header, except for HS256 tokens, which predate key
+// sets and have none.
+type KeySet struct {
+	keys []*Key
+}
+
+// NewKeySet builds a key set from keys in order of preference. At least one
+// active key has to be able to sign.
+func NewKeySet(keys ...*Key) (*KeySet, error) {
+	ks := &KeySet{keys: keys}
+	if ks.signingKey() == nil {
+		return nil, fmt.Errorf("key set has no active signing key")
+	}
+	return ks, nil
+}
+
+// NewHMACKeySet returns a key set that signs with a single shared HS256
+// secret, the way Chirpy worked before key sets existed.
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{keys: []*Key{NewHMACKey(secret, KeyActive)}}
+}
+
+func NewHMACKey(secret string, status KeyStatus) *Key {
+	return &Key{
+		Status:    status,
+		method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// NewKey wraps an Ed25519 or RSA key, private or public. Its ID is the
+// RFC 7638 thumbprint of the public key, so it's the same on every instance.
+func NewKey(key interface{}, status KeyStatus) (*Key, error) {
+	k := &Key{Status: status}
+
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		k.method, k.signKey, k.verifyKey = jwt.SigningMethodEdDSA, key, key.Public()
+	case ed25519.PublicKey:
+		k.method, k.verifyKey = jwt.SigningMethodEdDSA, key
+	case *rsa.PrivateKey:
+		k.method, k.signKey, k.verifyKey = jwt.SigningMethodRS256, key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.method, k.verifyKey = jwt.SigningMethodRS256, key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	if pub, ok := k.verifyKey.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+	}
+
+	thumbprint, err := json.Marshal(k.thumbprintMembers())
+	if err != nil {
+		return nil, fmt.Errorf("error computing key ID: %w", err)
+	}
+	sum := sha256.Sum256(thumbprint)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return k, nil
+}
+
+// ParseKeyPEM reads a PKCS #8 private key, a PKCS #1 RSA private key or a
+// PKIX public key.
+func ParseKeyPEM(data []byte, status KeyStatus) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", block.Type, err)
+	}
+
+	return NewKey(key, status)
+}
+
+// LoadKeySet reads the PEM files for the active and retiring keys. Without
+// any active key files it falls back to HS256 with secret. Otherwise secret,
+// if set, is only used to accept HS256 tokens issued before the switch.
+func LoadKeySet(activePaths, retiringPaths []string, secret string) (*KeySet, error) {
+	if len(activePaths) == 0 {
+		if secret == "" {
+			return nil, fmt.Errorf("no signing keys or secret configured")
+		}
+		return NewHMACKeySet(secret), nil
+	}
+
+	var keys []*Key
+	for _, paths := range []struct {
+		status KeyStatus
+		files  []string
+	}{{KeyActive, activePaths}, {KeyRetiring, retiringPaths}} {
+		for _, path := range paths.files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading key: %w", err)
+			}
+			key, err := ParseKeyPEM(data, paths.status)
+			if err != nil {
+				return nil, fmt.Errorf("error loading key %s: %w", path, err)
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if secret != "" {
+		keys = append(keys, NewHMACKey(secret, KeyRetiring))
+	}
+
+	return NewKeySet(keys...)
+}
+
+func (ks *KeySet) signingKey() *Key {
+	for _, k := range ks.keys {
+		if k.Status == KeyActive && k.signKey != nil {
+			return k
+		}
+	}
+	return nil
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	k := ks.signingKey()
+	if k == nil {
+		return "", fmt.Errorf("no signing key available")
+	}
+
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.ID != "" {
+		token.Header["kid"] = k.ID
+	}
+
+	return token.SignedString(k.signKey)
+}
+
+// keyFunc finds the key a token claims to be signed with. The algorithm has
+// to match the key, so an RS256 public key can never be used as an HMAC
+// secret.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	for _, k := range ks.keys {
+		if k.ID != kid {
+			continue
+		}
+		if token.Method.Alg() != k.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return k.verifyKey, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWK is the public half of a key as published in the JWKS document.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *Key) jwk() JWK {
+	switch pub := k.verifyKey.(type) {
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(pub)}
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	}
+	return JWK{}
+}
+
+// thumbprintMembers returns the required JWK members RFC 7638 hashes. They
+// go in a map because encoding/json sorts map keys, which is the order the
+// thumbprint needs.
+func (k *Key) thumbprintMembers() map[string]string {
+	jwk := k.jwk()
+	members := map[string]string{"kty": jwk.Kty}
+	switch jwk.Kty {
+	case "OKP":
+		members["crv"], members["x"] = jwk.Crv, jwk.X
+	case "RSA":
+		members["n"], members["e"] = jwk.N, jwk.E
+	}
+	return members
+}
+
+// JWKS returns the public keys other services need to verify our tokens.
+// HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		if k.ID == "" {
+			continue
+		}
+		jwk := k.jwk()
+		jwk.Kid = k.ID
+		jwk.Alg = k.method.Alg()
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func newEd25519Key(t *testing.T, status KeyStatus) *Key {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating Ed25519 key: %v", err)
+	}
+	key, err := NewKey(priv, status)
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	return key
+}
+
+func newRSAKey(t *testing.T, status KeyStatus) *Key {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating RSA key: %v", err)
+	}
+	key, err := NewKey(priv, status)
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	return key
+}
+
+func TestKeySetSignAndValidate(t *testing.T) {
+	for name, key := range map[string]*Key{
+		"EdDSA": newEd25519Key(t, KeyActive),
+		"RS256": newRSAKey(t, KeyActive),
+	} {
+		ks, err := NewKeySet(key)
+		if err != nil {
+			t.Fatalf("%s: NewKeySet: %v", name, err)
+		}
+
+		userID, sessionID := uuid.New(), uuid.New()
+		token, err := ks.MakeSessionJWT(userID, sessionID, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: error creating JWT: %v", name, err)
+		}
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+		if err != nil {
+			t.Fatalf("%s: error parsing JWT: %v", name, err)
+		}
+		if parsed.Header["alg"] != name || parsed.Header["kid"] != key.ID {
+			t.Errorf("%s: got header %v", name, parsed.Header)
+		}
+
+		gotUser, gotSession, err := ks.ValidateSessionJWT(token)
+		if err != nil {
+			t.Fatalf("%s: error validating JWT: %v", name, err)
+		}
+		if gotUser != userID || gotSession != sessionID {
+			t.Errorf("%s: got user %v session %v, want %v %v", name, gotUser, gotSession, userID, sessionID)
+		}
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey := newEd25519Key(t, KeyActive)
+	oldSet, err := NewKeySet(oldKey)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	oldToken, err := oldSet.MakeJWT(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	// rotate: a new key takes over and the old one only verifies
+	newKey := newRSAKey(t, KeyActive)
+	oldKey.Status = KeyRetiring
+	rotated, err := NewKeySet(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	if _, err := rotated.ValidateJWT(oldToken); err != nil {
+		t.Errorf("token signed with retiring key was rejected: %v", err)
+	}
+
+	newToken, err := rotated.MakeJWT(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+	if _, err := oldSet.ValidateJWT(newToken); err == nil {
+		t.Errorf("expected old key set to reject a token from the new key")
+	}
+
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != newKey.ID || jwks.Keys[1].Kid != oldKey.ID {
+		t.Errorf("JWKS doesn't list both keys in order: %+v", jwks.Keys)
+	}
+
+	// once the old key is gone so are its tokens
+	final, err := NewKeySet(newKey)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	if _, err := final.ValidateJWT(oldToken); err == nil {
+		t.Errorf("expected token from a removed key to be rejected")
+	}
+}
+
+func TestKeySetNeedsSigningKey(t *testing.T) {
+	if _, err := NewKeySet(newEd25519Key(t, KeyRetiring)); err == nil {
+		t.Errorf("expected error for key set without an active key")
+	}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	verifyOnly, err := NewKey(pub, KeyActive)
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	if _, err := NewKeySet(verifyOnly); err == nil {
+		t.Errorf("expected error for key set without a private key")
+	}
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	key := newRSAKey(t, KeyActive)
+	ks, err := NewKeySet(key)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	// an attacker signs an HS256 token using the published public key as
+	// the HMAC secret and points kid at it
+	pubDER, err := x509.MarshalPKIXPublicKey(key.verifyKey)
+	if err != nil {
+		t.Fatalf("error marshaling public key: %v", err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    accessTokenIssuer,
+			Subject:   uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	forged.Header["kid"] = key.ID
+	token, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	if err != nil {
+		t.Fatalf("error signing forged token: %v", err)
+	}
+
+	if _, err := ks.ValidateJWT(token); err == nil {
+		t.Errorf("expected forged HS256 token to be rejected")
+	}
+}
+
+func TestKeyIDIsRFC7638Thumbprint(t *testing.T) {
+	// example key from RFC 7638 section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatalf("error decoding modulus: %v", err)
+	}
+
+	key, err := NewKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}, KeyRetiring)
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+
+	if want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"; key.ID != want {
+		t.Errorf("key ID = %q, want %q", key.ID, want)
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("error marshaling key: %v", err)
+	}
+	path := filepath.Join(dir, "signing.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+
+	// without key files the secret signs, as before
+	hmacSet, err := LoadKeySet(nil, nil, "testsecret")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	legacy, err := hmacSet.MakeJWT(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+	if len(hmacSet.JWKS().Keys) != 0 {
+		t.Errorf("HMAC secret must not be published")
+	}
+
+	// with key files the secret still verifies tokens issued before the switch
+	ks, err := LoadKeySet([]string{path}, nil, "testsecret")
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	if _, err := ks.ValidateJWT(legacy); err != nil {
+		t.Errorf("legacy HS256 token was rejected: %v", err)
+	}
+
+	token, err := ks.MakeJWT(uuid.New(), time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("error parsing JWT: %v", err)
+	}
+	if parsed.Header["alg"] != "EdDSA" {
+		t.Errorf("expected EdDSA token, got %v", parsed.Header["alg"])
+	}
+
+	if len(ks.JWKS().Keys) != 1 {
+		t.Errorf("expected one published key, got %+v", ks.JWKS().Keys)
+	}
+
+	if _, err := LoadKeySet(nil, nil, ""); err == nil {
+		t.Errorf("expected error without keys or secret")
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2Params tune argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP password storage recommendation.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var (
+	passwordParamsMu sync.RWMutex
+	passwordParams   = DefaultArgon2Params
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// SetPasswordParams changes the parameters HashPassword uses. Hashes made
+// with other parameters keep verifying, NeedsRehash reports them.
+func SetPasswordParams(p Argon2Params) error {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 || p.SaltLength < 8 || p.KeyLength < 16 {
+		return fmt.Errorf("invalid argon2id parameters: %+v", p)
+	}
+
+	passwordParamsMu.Lock()
+	defer passwordParamsMu.Unlock()
+	passwordParams = p
+	return nil
+}
+
+func currentPasswordParams() Argon2Params {
+	passwordParamsMu.RLock()
+	defer passwordParamsMu.RUnlock()
+	return passwordParams
+}
+
+// HashPassword hashes with argon2id and returns the hash in the PHC string
+// format, e.g. $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>, so the
+// parameters travel with the hash.
+func HashPassword(password string) (string, error) {
+	return hashArgon2id(password, currentPasswordParams())
+}
+
+func hashArgon2id(password string, p Argon2Params) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPasswordHash verifies both argon2id hashes and the bcrypt hashes
+// stored before we switched.
+func CheckPasswordHash(hash, password string) error {
+	if isBcryptHash(hash) {
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			return fmt.Errorf("error comparing hash and password: %w", err)
+		}
+		return nil
+	}
+
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return fmt.Errorf("error comparing hash and password: %w", err)
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return fmt.Errorf("error comparing hash and password: password doesn't match")
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether hash uses an old algorithm or parameters
+// other than the current ones. Call it after a successful CheckPasswordHash,
+// while the plain password is at hand.
+func NeedsRehash(hash string) bool {
+	if isBcryptHash(hash) {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+
+	current := currentPasswordParams()
+	return p.Memory != current.Memory ||
+		p.Iterations != current.Iterations ||
+		p.Parallelism != current.Parallelism ||
+		p.SaltLength != current.SaltLength ||
+		p.KeyLength != current.KeyLength
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func decodeArgon2id(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return p, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	if p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errMalformedHash
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashAndCheckPassword(t *testing.T) {
+	hash, err := HashPassword("04234")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+
+	if err := CheckPasswordHash(hash, "04234"); err != nil {
+		t.Errorf("correct password rejected: %v", err)
+	}
+	if err := CheckPasswordHash(hash, "04235"); err == nil {
+		t.Errorf("wrong password accepted")
+	}
+
+	other, err := HashPassword("04234")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if other == hash {
+		t.Errorf("hashes of the same password should be salted differently")
+	}
+
+	if NeedsRehash(hash) {
+		t.Errorf("fresh hash shouldn't need a rehash")
+	}
+}
+
+func TestCheckLegacyBcryptHash(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("04234"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt: %v", err)
+	}
+
+	if err := CheckPasswordHash(string(legacy), "04234"); err != nil {
+		t.Errorf("bcrypt hash rejected: %v", err)
+	}
+	if err := CheckPasswordHash(string(legacy), "wrong"); err == nil {
+		t.Errorf("wrong password accepted for bcrypt hash")
+	}
+
+	if !NeedsRehash(string(legacy)) {
+		t.Errorf("bcrypt hash should need a rehash")
+	}
+}
+
+func TestNeedsRehashAfterParamsChange(t *testing.T) {
+	hash, err := HashPassword("04234")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	stronger := DefaultArgon2Params
+	stronger.Iterations++
+	if err := SetPasswordParams(stronger); err != nil {
+		t.Fatalf("SetPasswordParams: %v", err)
+	}
+	defer SetPasswordParams(DefaultArgon2Params)
+
+	if !NeedsRehash(hash) {
+		t.Errorf("hash with old parameters should need a rehash")
+	}
+
+	// the old hash still verifies with its own parameters
+	if err := CheckPasswordHash(hash, "04234"); err != nil {
+		t.Errorf("old hash rejected after params change: %v", err)
+	}
+
+	rehashed, err := HashPassword("04234")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if NeedsRehash(rehashed) {
+		t.Errorf("hash with current parameters shouldn't need a rehash")
+	}
+}
+
+func TestSetPasswordParamsRejectsWeakParams(t *testing.T) {
+	weak := DefaultArgon2Params
+	weak.Iterations = 0
+	if err := SetPasswordParams(weak); err == nil {
+		t.Errorf("expected error for zero iterations")
+	}
+
+	weak = DefaultArgon2Params
+	weak.SaltLength = 4
+	if err := SetPasswordParams(weak); err == nil {
+		t.Errorf("expected error for short salt")
+	}
+}
+
+func TestCheckMalformedHash(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plaintext",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"$argon2i$v=19$m=19456,t=2,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=16$m=19456,t=2,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHQ$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$!!!$a2V5",
+	} {
+		if err := CheckPasswordHash(hash, "04234"); err == nil {
+			t.Errorf("CheckPasswordHash(%q) accepted a malformed hash", hash)
+		}
+		if !NeedsRehash(hash) {
+			t.Errorf("NeedsRehash(%q) = false for a malformed hash", hash)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package auth
+
+import "strings"
+
+// PersonalAccessTokenPrefix marks long-lived tokens for bots, so they can be
+// told apart from JWTs and spotted by secret scanners.
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// HashPersonalAccessToken returns the value stored in place of the token.
+func HashPersonalAccessToken(token string) string {
+	return HashToken(token)
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("error creating token: %v", err)
+	}
+
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("token %q doesn't have the PAT prefix", token)
+	}
+
+	other, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("error creating token: %v", err)
+	}
+	if HashPersonalAccessToken(token) == HashPersonalAccessToken(other) {
+		t.Errorf("different tokens hashed to the same value")
+	}
+	if HashPersonalAccessToken(token) != HashPersonalAccessToken(token) {
+		t.Errorf("hash isn't stable")
+	}
+
+	jwt, err := MakeJWT(uuid.New(), "testsecret", time.Minute)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+	if IsPersonalAccessToken(jwt) {
+		t.Errorf("JWT mistaken for a PAT")
+	}
+}
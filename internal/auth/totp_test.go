@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key from the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	// The RFC lists 8 digit codes, we use the last 6.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		name    string
+		step    int64
+		wantErr bool
+	}{
+		{"current step", step, false},
+		{"previous step", step - 1, false},
+		{"next step", step + 1, false},
+		{"too old", step - 2, true},
+		{"too new", step + 2, true},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, tt.step)
+		if err != nil {
+			t.Fatalf("%s: TOTPCode: %v", tt.name, err)
+		}
+
+		got, err := ValidateTOTP(rfcSecret, code, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got none", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		} else if got != tt.step {
+			t.Errorf("%s: matched step %d, want %d", tt.name, got, tt.step)
+		}
+	}
+
+	if _, err := ValidateTOTP(rfcSecret, "12345", now); err == nil {
+		t.Errorf("expected error for short code, got none")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Chirpy", "walt@breakingbad.com", "JBSWY3DPEHPK3PXP")
+
+	for _, want := range []string{"otpauth://totp/Chirpy:walt@breakingbad.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Chirpy"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("TOTPURI() = %q, missing %q", uri, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		hash := HashRecoveryCode(code)
+		if seen[hash] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[hash] = true
+
+		// users may type the code without the dash or in upper case
+		if HashRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) != hash {
+			t.Errorf("HashRecoveryCode isn't normalizing %q", code)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Polka signs each webhook with HMAC-SHA256 over "<timestamp>.<body>" and
+// sends the hex digest in WebhookSignatureHeader as "v1=<digest>". While a
+// secret is being rotated it sends one v1= entry per secret, comma separated.
+const (
+	WebhookSignatureHeader = "X-Polka-Signature"
+	WebhookTimestampHeader = "X-Polka-Timestamp"
+)
+
+// DefaultWebhookTolerance is how far a webhook's timestamp may be from our
+// clock. Anything older is treated as a replay.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+var (
+	ErrWebhookTimestamp = errors.New("webhook timestamp missing or outside the tolerance")
+	ErrWebhookSignature = errors.New("webhook signature missing or invalid")
+)
+
+// SignWebhook returns the signature header value for body sent at timestamp.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	return "v1=" + hex.EncodeToString(webhookMAC(secret, timestamp.Unix(), body))
+}
+
+func webhookMAC(secret string, timestamp int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// VerifyWebhook checks Polka's signature headers against the raw body. Any
+// one of secrets is accepted so the old and the new secret both work during
+// a rotation.
+func VerifyWebhook(headers http.Header, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	return VerifyWebhookSignature(headers.Get(WebhookTimestampHeader), headers.Get(WebhookSignatureHeader), body, secrets, tolerance, now)
+}
+
+// VerifyWebhookSignature checks a signature made by SignWebhook, whichever
+// headers it came in.
+func VerifyWebhookSignature(timestampHeader, signatureHeader string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrWebhookTimestamp
+	}
+
+	skew := now.Sub(time.Unix(timestamp, 0))
+	if skew > tolerance || skew < -tolerance {
+		return ErrWebhookTimestamp
+	}
+
+	var signatures [][]byte
+	for _, part := range strings.Split(signatureHeader, ",") {
+		digest, ok := strings.CutPrefix(strings.TrimSpace(part), "v1=")
+		if !ok {
+			continue
+		}
+		sig, err := hex.DecodeString(digest)
+		if err != nil {
+			continue
+		}
+		signatures = append(signatures, sig)
+	}
+
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		expected := webhookMAC(secret, timestamp, body)
+		for _, sig := range signatures {
+			if hmac.Equal(expected, sig) {
+				return nil
+			}
+		}
+	}
+
+	return ErrWebhookSignature
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	now := time.Unix(1700000000, 0)
+
+	signed := func(signature string, timestamp time.Time) http.Header {
+		headers := http.Header{}
+		headers.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		headers.Set(WebhookSignatureHeader, signature)
+		return headers
+	}
+
+	tests := []struct {
+		name    string
+		headers http.Header
+		body    []byte
+		secrets []string
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			headers: signed(SignWebhook("current", now, body), now),
+			body:    body,
+			secrets: []string{"current"},
+		},
+		{
+			name:    "signed with the previous secret",
+			headers: signed(SignWebhook("previous", now, body), now),
+			body:    body,
+			secrets: []string{"current", "previous"},
+		},
+		{
+			name:    "one of several signatures matches",
+			headers: signed(SignWebhook("retired", now, body)+", "+SignWebhook("current", now, body), now),
+			body:    body,
+			secrets: []string{"current"},
+		},
+		{
+			name:    "within the tolerance",
+			headers: signed(SignWebhook("current", now.Add(-4*time.Minute), body), now.Add(-4*time.Minute)),
+			body:    body,
+			secrets: []string{"current"},
+		},
+		{
+			name:    "wrong secret",
+			headers: signed(SignWebhook("other", now, body), now),
+			body:    body,
+			secrets: []string{"current"},
+			wantErr: ErrWebhookSignature,
+		},
+		{
+			name:    "tampered body",
+			headers: signed(SignWebhook("current", now, body), now),
+			body:    []byte(`{"event":"user.upgraded","data":{"user_id":"00000000-0000-0000-0000-000000000000"}}`),
+			secrets: []string{"current"},
+			wantErr: ErrWebhookSignature,
+		},
+		{
+			name:    "timestamp changed after signing",
+			headers: signed(SignWebhook("current", now.Add(-time.Minute), body), now),
+			body:    body,
+			secrets: []string{"current"},
+			wantErr: ErrWebhookSignature,
+		},
+		{
+			name:    "replayed too late",
+			headers: signed(SignWebhook("current", now.Add(-10*time.Minute), body), now.Add(-10*time.Minute)),
+			body:    body,
+			secrets: []string{"current"},
+			wantErr: ErrWebhookTimestamp,
+		},
+		{
+			name:    "from the future",
+			headers: signed(SignWebhook("current", now.Add(10*time.Minute), body), now.Add(10*time.Minute)),
+			body:    body,
+			secrets: []string{"current"},
+			wantErr: ErrWebhookTimestamp,
+		},
+		{
+			name:    "missing headers",
+			headers: http.Header{},
+			body:    body,
+			secrets: []string{"current"},
+			wantErr: ErrWebhookTimestamp,
+		},
+		{
+			name:    "malformed signature",
+			headers: signed("v1=not-hex", now),
+			body:    body,
+			secrets: []string{"current"},
+			wantErr: ErrWebhookSignature,
+		},
+		{
+			name:    "no secrets configured",
+			headers: signed(SignWebhook("", now, body), now),
+			body:    body,
+			secrets: []string{""},
+			wantErr: ErrWebhookSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(tt.headers, tt.body, tt.secrets, DefaultWebhookTolerance, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyWebhook() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
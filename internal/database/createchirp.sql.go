@@ -12,24 +12,26 @@ import (
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to)
 VALUES (
     gen_random_uuid(),
     NOW(), 
     NOW(), 
     $1,
-    $2
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.InReplyTo)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +39,9 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
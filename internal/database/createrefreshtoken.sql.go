@@ -7,32 +7,50 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip_address, last_used_at)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6,
+    $7,
+    NOW()
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip_address, last_used_at
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	ExpiresAt time.Time
+	Token       string
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	UserAgent   string
+	IpAddress   string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, createRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.ParentToken,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -41,6 +59,12 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
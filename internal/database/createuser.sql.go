@@ -7,27 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(), 
     NOW(), 
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, username, display_name, bio, is_admin, totp_secret, totp_enabled, totp_last_step, email_verified, pending_email
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Username       sql.NullString
+	DisplayName    string
+	Bio            string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Username,
+		arg.DisplayName,
+		arg.Bio,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -36,6 +49,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.IsAdmin,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.EmailVerified,
+		&i.PendingEmail,
 	)
 	return i, err
 }
@@ -12,7 +12,7 @@ import (
 )
 
 const getChirp = `-- name: GetChirp :one
-SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at
 FROM chirps
 WHERE chirps.id = $1
 `
@@ -26,6 +26,9 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
@@ -10,7 +10,7 @@ import (
 )
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT refresh_tokens.token, refresh_tokens.created_at, refresh_tokens.updated_at, refresh_tokens.user_id, refresh_tokens.expires_at, refresh_tokens.revoked_at
+SELECT refresh_tokens.token, refresh_tokens.created_at, refresh_tokens.updated_at, refresh_tokens.user_id, refresh_tokens.expires_at, refresh_tokens.revoked_at, refresh_tokens.family_id, refresh_tokens.parent_token, refresh_tokens.rotated_at, refresh_tokens.user_agent, refresh_tokens.ip_address, refresh_tokens.last_used_at
 FROM refresh_tokens
 WHERE refresh_tokens.token = $1
 `
@@ -25,6 +25,12 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -10,7 +10,7 @@ import (
 )
 
 const getUser = `-- name: GetUser :one
-SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.is_chirpy_red, users.username, users.display_name, users.bio, users.is_admin, users.totp_secret, users.totp_enabled, users.totp_last_step, users.email_verified, users.pending_email
 FROM users
 WHERE users.email = $1
 `
@@ -25,6 +25,15 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.Username,
+		&i.DisplayName,
+		&i.Bio,
+		&i.IsAdmin,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastStep,
+		&i.EmailVerified,
+		&i.PendingEmail,
 	)
 	return i, err
 }
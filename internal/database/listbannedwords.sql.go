@@ -1,7 +1,7 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
 //   sqlc v1.28.0
-// source: getchirps.sql
+// source: listbannedwords.sql
 
 package database
 
@@ -9,27 +9,25 @@ import (
 	"context"
 )
 
-const getChirps = `-- name: GetChirps :many
-SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id
-FROM chirps
-ORDER BY created_at ASC
+const listBannedWords = `-- name: ListBannedWords :many
+SELECT banned_words.word, banned_words.created_at, banned_words.created_by
+FROM banned_words
+ORDER BY banned_words.word ASC
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) ListBannedWords(ctx context.Context) ([]BannedWord, error) {
+	rows, err := q.db.QueryContext(ctx, listBannedWords)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []BannedWord
 	for rows.Next() {
-		var i Chirp
+		var i BannedWord
 		if err := rows.Scan(
-			&i.ID,
+			&i.Word,
 			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Body,
-			&i.UserID,
+			&i.CreatedBy,
 		); err != nil {
 			return nil, err
 		}
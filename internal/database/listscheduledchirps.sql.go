@@ -1,7 +1,7 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
 //   sqlc v1.28.0
-// source: getchirpsbyauthor.sql
+// source: listscheduledchirps.sql
 
 package database
 
@@ -11,27 +11,33 @@ import (
 	"github.com/google/uuid"
 )
 
-const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id FROM chirps
+const listScheduledChirps = `-- name: ListScheduledChirps :many
+SELECT * FROM scheduled_chirps
 WHERE user_id = $1
-ORDER BY created_at ASC
+ORDER BY publish_at ASC, id ASC
 `
 
-func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, userID)
+func (q *Queries) ListScheduledChirps(ctx context.Context, userID uuid.UUID) ([]ScheduledChirp, error) {
+	rows, err := q.db.QueryContext(ctx, listScheduledChirps, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []ScheduledChirp
 	for rows.Next() {
-		var i Chirp
+		var i ScheduledChirp
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Body,
 			&i.UserID,
+			&i.Body,
+			&i.PublishAt,
+			&i.Status,
+			&i.Error,
+			&i.ChirpID,
+			&i.Attempts,
+			&i.RetryAt,
 		); err != nil {
 			return nil, err
 		}
@@ -11,21 +11,143 @@ import (
 	"github.com/google/uuid"
 )
 
+type BannedWord struct {
+	Word      string
+	CreatedAt time.Time
+	CreatedBy uuid.NullUUID
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	DeletedAt sql.NullTime
+	EditedAt  sql.NullTime
+}
+
+type ChirpLike struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+	CreatedAt   time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginThrottle struct {
+	Key           string
+	Failures      int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	TokenHint  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
 }
 
 type RefreshToken struct {
-	Token     string
+	Token       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	RotatedAt   sql.NullTime
+	UserAgent   string
+	IpAddress   string
+	LastUsedAt  time.Time
+}
+
+type ScheduledChirp struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    uuid.UUID
+	Body      string
+	PublishAt time.Time
+	Status    string
+	Error     sql.NullString
+	ChirpID   uuid.NullUUID
+	Attempts  int32
+	RetryAt   sql.NullTime
+}
+
+type SecurityEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	EventType string
+	Details   string
+}
+
+type Subscription struct {
+	UserID           uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+}
+
+type UsedLoginChallenge struct {
+	ID        uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
 }
 
 type User struct {
@@ -35,4 +157,52 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Username       sql.NullString
+	DisplayName    string
+	Bio            string
+	IsAdmin        bool
+	TotpSecret     sql.NullString
+	TotpEnabled    bool
+	TotpLastStep   int64
+	EmailVerified  bool
+	PendingEmail   sql.NullString
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	SubscriptionID uuid.UUID
+	EventID        uuid.UUID
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	ResponseStatus sql.NullInt32
+	Error          sql.NullString
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	EventID     string
+	EventType   string
+	Payload     string
+	Status      string
+	Error       sql.NullString
+	Attempts    int32
+	ProcessedAt sql.NullTime
+}
+
+type WebhookSubscription struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+	Events    []string
+	Secret    string
 }
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/moderation"
+)
+
+const maxBannedWordLength = 50
+
+type BannedWord struct {
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReloadBannedWords refreshes the in-memory profanity filter from the
+// database. It runs on startup and after every change to the list; other
+// server instances pick the change up on their next RunBannedWordsReloader
+// tick.
+func (cfg *ApiConfig) ReloadBannedWords(ctx context.Context) error {
+	rows, err := cfg.DbQueries.ListBannedWords(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading banned words: %w", err)
+	}
+
+	words := make([]string, len(rows))
+	for i, row := range rows {
+		words[i] = row.Word
+	}
+
+	if cfg.Profanity == nil {
+		cfg.Profanity = moderation.NewFilter(words)
+		return nil
+	}
+	cfg.Profanity.Replace(words)
+	return nil
+}
+
+// RunBannedWordsReloader calls ReloadBannedWords every interval until ctx is
+// done, so changes made through another instance reach this one.
+func (cfg *ApiConfig) RunBannedWordsReloader(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := cfg.ReloadBannedWords(ctx); err != nil {
+			log.Printf("Error reloading banned words: %v", err)
+		}
+	}
+}
+
+func (cfg *ApiConfig) ListBannedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.DbQueries.ListBannedWords(r.Context())
+	if err != nil {
+		respondWithError(w, "Error getting banned words", http.StatusInternalServerError)
+		return
+	}
+
+	words := []BannedWord{}
+	for _, row := range rows {
+		words = append(words, BannedWord{Word: row.Word, CreatedAt: row.CreatedAt})
+	}
+
+	respondWithJSON(w, words, http.StatusOK)
+}
+
+func (cfg *ApiConfig) CreateBannedWordHandler(w http.ResponseWriter, r *http.Request) {
+	type BannedWordRequest struct {
+		Word string `json:"word"`
+	}
+
+	var req BannedWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Error decoding JSON in BannedWordRequest", http.StatusBadRequest)
+		return
+	}
+
+	word := strings.ToLower(strings.TrimSpace(req.Word))
+	if word == "" || strings.ContainsFunc(word, unicode.IsSpace) || len(word) > maxBannedWordLength {
+		respondWithError(w, "Word must be a single word", http.StatusBadRequest)
+		return
+	}
+	if moderation.Skeleton(word) == "" {
+		respondWithError(w, "Word must contain letters", http.StatusBadRequest)
+		return
+	}
+
+	// MiddlewareAdmin already checked the token
+	adminID, _ := cfg.ValidateAccessToken(r.Context(), r.Header)
+
+	row, err := cfg.DbQueries.CreateBannedWord(r.Context(), database.CreateBannedWordParams{
+		Word:      word,
+		CreatedBy: uuid.NullUUID{UUID: adminID, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Word is already banned", http.StatusConflict)
+			return
+		}
+		respondWithError(w, "Error banning word", http.StatusInternalServerError)
+		return
+	}
+
+	if err := cfg.ReloadBannedWords(r.Context()); err != nil {
+		respondWithError(w, "Error refreshing banned words", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, BannedWord{Word: row.Word, CreatedAt: row.CreatedAt}, http.StatusCreated)
+}
+
+func (cfg *ApiConfig) DeleteBannedWordHandler(w http.ResponseWriter, r *http.Request) {
+	word := strings.ToLower(r.PathValue("word"))
+
+	deleted, err := cfg.DbQueries.DeleteBannedWord(r.Context(), word)
+	if err != nil {
+		respondWithError(w, "Error removing banned word", http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, "Word isn't banned", http.StatusNotFound)
+		return
+	}
+
+	if err := cfg.ReloadBannedWords(r.Context()); err != nil {
+		respondWithError(w, "Error refreshing banned words", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/realquiller/chirpy_server/internal/moderation"
+)
+
+// Banning and unbanning a word takes effect in this instance's filter
+// straight away.
+func TestBannedWordHandlers(t *testing.T) {
+	words := map[string]time.Time{}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"CreateBannedWord": func(args []driver.Value) ([][]driver.Value, error) {
+			word := args[0].(string)
+			if _, ok := words[word]; ok {
+				return nil, nil
+			}
+			words[word] = time.Now()
+			return [][]driver.Value{{word, words[word], args[1]}}, nil
+		},
+		"DeleteBannedWord": func(args []driver.Value) ([][]driver.Value, error) {
+			word := args[0].(string)
+			if _, ok := words[word]; !ok {
+				return nil, nil
+			}
+			delete(words, word)
+			return [][]driver.Value{{word}}, nil
+		},
+		"ListBannedWords": func(args []driver.Value) ([][]driver.Value, error) {
+			names := []string{}
+			for word := range words {
+				names = append(names, word)
+			}
+			sort.Strings(names)
+
+			rows := [][]driver.Value{}
+			for _, word := range names {
+				rows = append(rows, []driver.Value{word, words[word], nil})
+			}
+			return rows, nil
+		},
+	})
+
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Profanity: moderation.NewFilter(nil)}
+
+	create := func(body string) int {
+		rec := httptest.NewRecorder()
+		cfg.CreateBannedWordHandler(rec, httptest.NewRequest(http.MethodPost, "/admin/banned-words", strings.NewReader(body)))
+		return rec.Code
+	}
+	remove := func(word string) int {
+		req := httptest.NewRequest(http.MethodDelete, "/admin/banned-words/"+word, nil)
+		req.SetPathValue("word", word)
+		rec := httptest.NewRecorder()
+		cfg.DeleteBannedWordHandler(rec, req)
+		return rec.Code
+	}
+
+	if code := create(`{"word":"  Kerfuffle "}`); code != http.StatusCreated {
+		t.Fatalf("ban: status %d, want 201", code)
+	}
+	if got := cfg.Profanity.Clean("what a kerfuffle"); got != "what a ****" {
+		t.Errorf("after banning, Clean = %q", got)
+	}
+	if code := create(`{"word":"kerfuffle"}`); code != http.StatusConflict {
+		t.Errorf("banning twice: status %d, want 409", code)
+	}
+
+	for _, body := range []string{`{"word":""}`, `{"word":"two words"}`, `{"word":"1234"}`, `nope`} {
+		if code := create(body); code != http.StatusBadRequest {
+			t.Errorf("ban %s: status %d, want 400", body, code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	cfg.ListBannedWordsHandler(rec, httptest.NewRequest(http.MethodGet, "/admin/banned-words", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"word":"kerfuffle"`) {
+		t.Errorf("list: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if code := remove("KERFUFFLE"); code != http.StatusNoContent {
+		t.Fatalf("unban: status %d, want 204", code)
+	}
+	if got := cfg.Profanity.Clean("what a kerfuffle"); got != "what a kerfuffle" {
+		t.Errorf("after unbanning, Clean = %q", got)
+	}
+	if code := remove("kerfuffle"); code != http.StatusNotFound {
+		t.Errorf("unbanning twice: status %d, want 404", code)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// EditChirpHandler replaces the body of one of the user's chirps. It sits
+// behind MiddlewareRequireFeature, which hands over the user.
+func (cfg *ApiConfig) EditChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type EditChirpRequest struct {
+		Body string `json:"body"`
+	}
+
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	var req EditChirpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Failed to parse chirp", http.StatusBadRequest)
+		return
+	}
+
+	chirp, err := cfg.DbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, "Chirp wasn't found", http.StatusNotFound)
+		return
+	}
+
+	if chirp.UserID != user.ID {
+		respondWithError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	body, verr := cfg.validateChirp(req.Body, user)
+	if verr != nil {
+		respondWithValidationError(w, verr)
+		return
+	}
+
+	if wait, ok := cfg.allowChirpWrite(user); !ok {
+		respondWithRateLimit(w, wait)
+		return
+	}
+
+	chirp, err = cfg.editChirp(r.Context(), chirpID, body)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Chirp wasn't found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Failed to edit chirp", http.StatusInternalServerError)
+		return
+	}
+
+	chirp_list := []Chirp{chirpFromDB(chirp)}
+
+	if err := cfg.decorateChirps(r.Context(), chirp_list, uuid.NullUUID{UUID: user.ID, Valid: true}); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, chirp_list[0], http.StatusOK)
+}
+
+// editChirp swaps in the new body and works out its hashtags and mentions
+// again, in a single transaction. Hashtags the chirp already had keep the
+// time they were first used, so editing a chirp can't push its tags back up
+// the trending list.
+func (cfg *ApiConfig) editChirp(ctx context.Context, chirpID uuid.UUID, body string) (database.Chirp, error) {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	chirp, err := qtx.UpdateChirpBody(ctx, database.UpdateChirpBodyParams{
+		ID:   chirpID,
+		Body: body,
+	})
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("error updating chirp: %w", err)
+	}
+
+	err = qtx.DeleteChirpTagsExcept(ctx, database.DeleteChirpTagsExceptParams{
+		ChirpID: chirpID,
+		Tags:    extractHashtags(body),
+	})
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("error removing hashtags: %w", err)
+	}
+	if err := qtx.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return database.Chirp{}, fmt.Errorf("error removing mentions: %w", err)
+	}
+
+	if err := saveChirpBodyLinks(ctx, qtx, chirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.Chirp{}, fmt.Errorf("error committing chirp: %w", err)
+	}
+
+	return chirp, nil
+}
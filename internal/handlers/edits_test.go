@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/moderation"
+)
+
+// fakeChirp is a chirps row kept by the edit tests.
+type fakeChirp struct {
+	id       uuid.UUID
+	userID   uuid.UUID
+	body     string
+	deleted  bool
+	editedAt any
+}
+
+func (c *fakeChirp) row() []driver.Value {
+	var deletedAt any
+	if c.deleted {
+		deletedAt = time.Now()
+	}
+	now := time.Now()
+	return []driver.Value{c.id.String(), now, now, c.body, c.userID.String(), nil, deletedAt, c.editedAt}
+}
+
+// TestEditChirp edits a chirp, then tries chirps that can't be edited. An
+// edit marks the chirp edited, and the hashtags it keeps keep the time they
+// were first used.
+func TestEditChirp(t *testing.T) {
+	author := database.User{ID: uuid.New(), IsChirpyRed: true}
+	posted := time.Now().Add(-24 * time.Hour)
+
+	mine := &fakeChirp{id: uuid.New(), userID: author.ID, body: "Respect the #chemistry and the #science"}
+	theirs := &fakeChirp{id: uuid.New(), userID: uuid.New(), body: "Better call Saul"}
+	deleted := &fakeChirp{id: uuid.New(), userID: author.ID, body: "Tread lightly", deleted: true}
+	// deleted between the lookup and the update
+	racing := &fakeChirp{id: uuid.New(), userID: author.ID, body: "Say my name"}
+	chirps := []*fakeChirp{mine, theirs, deleted, racing}
+
+	tags := map[string]time.Time{"chemistry": posted, "science": posted}
+
+	find := func(id driver.Value) *fakeChirp {
+		for _, c := range chirps {
+			if c.id.String() == id {
+				return c
+			}
+		}
+		return nil
+	}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"GetChirp": func(args []driver.Value) ([][]driver.Value, error) {
+			c := find(args[0])
+			if c == nil {
+				return nil, nil
+			}
+			row := c.row()
+			if c == racing {
+				c.deleted = true
+			}
+			return [][]driver.Value{row}, nil
+		},
+		"UpdateChirpBody": func(args []driver.Value) ([][]driver.Value, error) {
+			c := find(args[0])
+			if c == nil || c.deleted {
+				return nil, nil
+			}
+			c.body, c.editedAt = args[1].(string), time.Now()
+			return [][]driver.Value{c.row()}, nil
+		},
+		"DeleteChirpTagsExcept": func(args []driver.Value) ([][]driver.Value, error) {
+			keep := args[1].(string)
+			for tag := range tags {
+				if !strings.Contains(keep, tag) {
+					delete(tags, tag)
+				}
+			}
+			return nil, nil
+		},
+		"AddChirpTags": func(args []driver.Value) ([][]driver.Value, error) {
+			for _, tag := range strings.Split(strings.Trim(args[1].(string), "{}"), ",") {
+				tag = strings.Trim(tag, `"`)
+				// ON CONFLICT DO NOTHING
+				if _, ok := tags[tag]; !ok {
+					tags[tag] = time.Now()
+				}
+			}
+			return nil, nil
+		},
+		"DeleteChirpMentions": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"GetChirpLikeStats": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"GetChirpMentions": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Profanity: moderation.NewFilter(nil)}
+
+	edit := func(chirp *fakeChirp, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/chirps/"+chirp.id.String(), strings.NewReader(`{"body":"`+body+`"}`))
+		req.SetPathValue("chirpid", chirp.id.String())
+		req = req.WithContext(context.WithValue(req.Context(), userContextKey, author))
+		rec := httptest.NewRecorder()
+		cfg.EditChirpHandler(rec, req)
+		return rec
+	}
+
+	rec := edit(mine, "Respect the #chemistry and the #rv")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("edit returned %d: %s", rec.Code, rec.Body)
+	}
+	var edited Chirp
+	if err := json.NewDecoder(rec.Body).Decode(&edited); err != nil {
+		t.Fatalf("error decoding chirp: %v", err)
+	}
+	if edited.Body != "Respect the #chemistry and the #rv" || edited.EditedAt == nil {
+		t.Errorf("edit returned %q edited at %v", edited.Body, edited.EditedAt)
+	}
+
+	if !tags["chemistry"].Equal(posted) {
+		t.Errorf("#chemistry was re-dated to %v, want %v", tags["chemistry"], posted)
+	}
+	if _, ok := tags["science"]; ok {
+		t.Errorf("#science is still tagged after the edit removed it")
+	}
+	if added, ok := tags["rv"]; !ok || !added.After(posted) {
+		t.Errorf("#rv = %v, %v; want it tagged as of the edit", added, ok)
+	}
+
+	tests := []struct {
+		name  string
+		chirp *fakeChirp
+		want  int
+	}{
+		{"someone else's", theirs, http.StatusForbidden},
+		{"deleted", deleted, http.StatusNotFound},
+		{"deleted mid-edit", racing, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		before := tt.chirp.body
+		if rec := edit(tt.chirp, "Jesse, we need to cook"); rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.chirp.body != before || tt.chirp.editedAt != nil {
+			t.Errorf("%s: chirp was edited to %q", tt.name, tt.chirp.body)
+		}
+	}
+}
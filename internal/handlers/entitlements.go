@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// planFree is everyone without a subscription. Plans are ranked, a plan
+// includes every feature of the plans below it.
+const planFree = "free"
+
+var planRanks = map[string]int{
+	planFree: 0,
+	planRed:  1,
+}
+
+var planNames = map[string]string{
+	planFree: "Chirpy",
+	planRed:  "Chirpy Red",
+}
+
+// Features that depend on the user's plan.
+const (
+	FeatureLongChirps       = "long_chirps"
+	FeatureChirpEditing     = "chirp_editing"
+	FeatureHigherRateLimits = "higher_rate_limits"
+	FeatureScheduledChirps  = "scheduled_chirps"
+)
+
+// featurePolicy says which plan a feature needs. Scope is the token scope a
+// bot needs to use it through MiddlewareRequireFeature, empty if bots can't.
+type featurePolicy struct {
+	Name  string
+	Plan  string
+	Scope string
+}
+
+var featurePolicies = map[string]featurePolicy{
+	FeatureLongChirps:       {Name: "Chirps over 140 characters", Plan: planRed},
+	FeatureChirpEditing:     {Name: "Editing chirps", Plan: planRed, Scope: ScopeChirpsWrite},
+	FeatureHigherRateLimits: {Name: "Higher rate limits", Plan: planRed},
+	FeatureScheduledChirps:  {Name: "Scheduling chirps", Plan: planRed, Scope: ScopeChirpsWrite},
+}
+
+// EntitlementError is the body of every 402 and 403 caused by the user's
+// plan. It's 402 when subscribing would unlock the feature and 403 when the
+// user already pays for a plan that doesn't include it.
+type EntitlementError struct {
+	Error        string `json:"error"`
+	Code         string `json:"code"`
+	Feature      string `json:"feature"`
+	RequiredPlan string `json:"required_plan"`
+	status       int
+}
+
+func planOf(user database.User) string {
+	if user.IsChirpyRed {
+		return planRed
+	}
+	return planFree
+}
+
+// checkFeature returns nil if the user's plan includes feature.
+func checkFeature(user database.User, feature string) *EntitlementError {
+	policy, ok := featurePolicies[feature]
+	if !ok {
+		return &EntitlementError{
+			Error:   fmt.Sprintf("Unknown feature %q", feature),
+			Code:    "feature_unavailable",
+			Feature: feature,
+			status:  http.StatusForbidden,
+		}
+	}
+
+	plan := planOf(user)
+	if planRanks[plan] >= planRanks[policy.Plan] {
+		return nil
+	}
+
+	status := http.StatusPaymentRequired
+	if plan != planFree {
+		status = http.StatusForbidden
+	}
+
+	return &EntitlementError{
+		Error:        fmt.Sprintf("%s requires %s", policy.Name, planNames[policy.Plan]),
+		Code:         "plan_required",
+		Feature:      feature,
+		RequiredPlan: policy.Plan,
+		status:       status,
+	}
+}
+
+func hasFeature(user database.User, feature string) bool {
+	return checkFeature(user, feature) == nil
+}
+
+func respondWithEntitlementError(w http.ResponseWriter, eerr *EntitlementError) {
+	respondWithJSON(w, eerr, eerr.status)
+}
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// userFromContext returns the user MiddlewareRequireFeature let through.
+func userFromContext(ctx context.Context) (database.User, bool) {
+	user, ok := ctx.Value(userContextKey).(database.User)
+	return user, ok
+}
+
+// MiddlewareRequireFeature only lets through users whose plan includes
+// feature, and hands the user on to next in the request context.
+func (cfg *ApiConfig) MiddlewareRequireFeature(feature string, next http.Handler) http.Handler {
+	policy := featurePolicies[feature]
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var userID uuid.UUID
+		var err error
+		if policy.Scope != "" {
+			userID, err = cfg.ValidateScopedToken(r.Context(), r.Header, policy.Scope)
+		} else {
+			userID, err = cfg.ValidateAccessToken(r.Context(), r.Header)
+		}
+		if err != nil {
+			respondWithAuthError(w, err)
+			return
+		}
+
+		user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, "Error getting user", http.StatusInternalServerError)
+			return
+		}
+
+		if eerr := checkFeature(user, feature); eerr != nil {
+			respondWithEntitlementError(w, eerr)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+func TestCheckFeature(t *testing.T) {
+	regular := database.User{}
+	red := database.User{IsChirpyRed: true}
+
+	for feature, policy := range featurePolicies {
+		if _, ok := planRanks[policy.Plan]; !ok {
+			t.Errorf("%s: needs unknown plan %q", feature, policy.Plan)
+		}
+
+		if eerr := checkFeature(red, feature); eerr != nil {
+			t.Errorf("%s: Chirpy Red member refused: %+v", feature, eerr)
+		}
+
+		eerr := checkFeature(regular, feature)
+		if eerr == nil {
+			t.Errorf("%s: allowed without Chirpy Red", feature)
+			continue
+		}
+		if eerr.status != http.StatusPaymentRequired || eerr.Code != "plan_required" || eerr.RequiredPlan != planRed {
+			t.Errorf("%s: got %d %+v, want 402 naming the red plan", feature, eerr.status, eerr)
+		}
+	}
+
+	if eerr := checkFeature(red, "teleportation"); eerr == nil || eerr.status != http.StatusForbidden {
+		t.Errorf("unknown feature: got %+v, want 403", eerr)
+	}
+}
+
+func TestChirpRateLimitFor(t *testing.T) {
+	if got := chirpRateLimitFor(database.User{}); got != chirpRateLimit {
+		t.Errorf("regular limit = %d, want %d", got, chirpRateLimit)
+	}
+	if got := chirpRateLimitFor(database.User{IsChirpyRed: true}); got != redChirpRateLimit {
+		t.Errorf("Chirpy Red limit = %d, want %d", got, redChirpRateLimit)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// fakeQuery answers one sqlc query, returning its rows as driver values.
+type fakeQuery func(args []driver.Value) ([][]driver.Value, error)
+
+// fakeDB is a database/sql driver for tests that don't have Postgres. Each
+// sqlc query is answered by the fakeQuery registered under its name, and a
+// query without one fails the test. Tests that care how a transaction ended
+// can register "Commit" and "Rollback" too.
+type fakeDB struct {
+	t       *testing.T
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+}
+
+// newFakeDB opens queries as a database and returns it with its Queries.
+func newFakeDB(t *testing.T, queries map[string]fakeQuery) (*sql.DB, *database.Queries) {
+	db := sql.OpenDB(&fakeDB{t: t, queries: queries})
+	t.Cleanup(func() { db.Close() })
+	return db, database.New(db)
+}
+
+// fakeUserRow is user as the users.* queries return it.
+func fakeUserRow(user database.User) []driver.Value {
+	return []driver.Value{
+		user.ID.String(), user.CreatedAt, user.UpdatedAt, user.Email, user.HashedPassword,
+		user.IsChirpyRed, fakeNullString(user.Username), user.DisplayName, user.Bio, user.IsAdmin,
+		fakeNullString(user.TotpSecret), user.TotpEnabled, user.TotpLastStep, user.EmailVerified,
+		fakeNullString(user.PendingEmail),
+	}
+}
+
+func fakeNullString(s sql.NullString) driver.Value {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+func (f *fakeDB) run(query string, named []driver.NamedValue) ([][]driver.Value, error) {
+	// sqlc starts every query with "-- name: Name :kind"
+	name := strings.Fields(strings.TrimPrefix(query, "-- name: "))[0]
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, ok := f.queries[name]
+	if !ok {
+		f.t.Errorf("unexpected query %s", name)
+		return nil, fmt.Errorf("no fake for %s", name)
+	}
+
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	return q(args)
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements aren't supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{c.db}, nil }
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+// fakeTx doesn't roll anything back itself, it only tells the test.
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error   { return tx.end("Commit") }
+func (tx fakeTx) Rollback() error { return tx.end("Rollback") }
+
+func (tx fakeTx) end(name string) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	if q, ok := tx.db.queries[name]; ok {
+		_, err := q(nil)
+		return err
+	}
+	return nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	cols := make([]string, len(r.rows[0]))
+	for i := range cols {
+		cols[i] = fmt.Sprintf("c%d", i)
+	}
+	return cols
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *ApiConfig) FollowHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userid"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, "You can't follow yourself", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := cfg.DbQueries.GetUserByID(r.Context(), followeeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	err = cfg.DbQueries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, "Error following user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) UnfollowHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userid"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = cfg.DbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, "Error unfollowing user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) GetFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, true)
+}
+
+func (cfg *ApiConfig) GetFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, false)
+}
+
+// listFollows serves both directions of the follow graph, newest first.
+func (cfg *ApiConfig) listFollows(w http.ResponseWriter, r *http.Request, followers bool) {
+	userID, err := uuid.Parse(r.PathValue("userid"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page.Before != nil {
+		respondWithError(w, "before is not supported here", http.StatusBadRequest)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(page.After)
+
+	follows := []Follow{}
+	if followers {
+		rows, err := cfg.DbQueries.GetFollowers(r.Context(), database.GetFollowersParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			RowLimit:        page.Limit + 1,
+		})
+		if err != nil {
+			respondWithError(w, "Error getting followers", http.StatusInternalServerError)
+			return
+		}
+		for _, row := range rows {
+			follows = append(follows, Follow{UserID: row.UserID, FollowedAt: row.CreatedAt})
+		}
+	} else {
+		rows, err := cfg.DbQueries.GetFollowing(r.Context(), database.GetFollowingParams{
+			UserID:          userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			RowLimit:        page.Limit + 1,
+		})
+		if err != nil {
+			respondWithError(w, "Error getting followed users", http.StatusInternalServerError)
+			return
+		}
+		for _, row := range rows {
+			follows = append(follows, Follow{UserID: row.UserID, FollowedAt: row.CreatedAt})
+		}
+	}
+
+	respondWithJSON(w, newFollowPage(follows, page.Limit), http.StatusOK)
+}
+
+// newFollowPage builds the response for rows fetched with limit+1.
+func newFollowPage(follows []Follow, limit int32) FollowPage {
+	page := FollowPage{Users: follows}
+	if len(follows) > int(limit) {
+		page.Users = follows[:limit]
+		last := page.Users[len(page.Users)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.FollowedAt, ID: last.UserID})
+	}
+	return page
+}
+
+func (cfg *ApiConfig) TimelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateScopedToken(r.Context(), r.Header, ScopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page.Before != nil {
+		respondWithError(w, "before is not supported here", http.StatusBadRequest)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(page.After)
+
+	chirps, err := cfg.DbQueries.GetTimeline(r.Context(), database.GetTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, "Error getting timeline", http.StatusInternalServerError)
+		return
+	}
+
+	chirp_list := []Chirp{}
+	for _, chirp := range chirps {
+		chirp_list = append(chirp_list, chirpFromDB(chirp))
+	}
+
+	if err := cfg.decorateChirps(r.Context(), chirp_list, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
+	}
+
+	// the timeline only pages forwards, so there's no prev_cursor to hand out
+	respondWithJSON(w, newChirpPage(chirp_list, page.Limit, false, false), http.StatusOK)
+}
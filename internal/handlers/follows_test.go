@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewFollowPage(t *testing.T) {
+	base := time.Now()
+	follows := make([]Follow, 3)
+	for i := range follows {
+		follows[i] = Follow{UserID: uuid.New(), FollowedAt: base.Add(-time.Duration(i) * time.Minute)}
+	}
+
+	page := newFollowPage(follows, 2)
+	if len(page.Users) != 2 {
+		t.Fatalf("expected 2 users, got %d", len(page.Users))
+	}
+	next, err := decodeCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("error decoding next cursor: %v", err)
+	}
+	if next.ID != follows[1].UserID || !next.CreatedAt.Equal(follows[1].FollowedAt) {
+		t.Errorf("expected next cursor to point at %v, got %+v", follows[1].UserID, next)
+	}
+
+	page = newFollowPage(follows, 3)
+	if len(page.Users) != 3 || page.NextCursor != "" {
+		t.Errorf("expected the last page without a cursor, got %d users and %q", len(page.Users), page.NextCursor)
+	}
+}
+
+// None of these get as far as the database.
+func TestListFollowsRejectsBadRequests(t *testing.T) {
+	cfg := &ApiConfig{}
+	cursor := encodeCursor(pageCursor{CreatedAt: time.Now(), ID: uuid.New()})
+
+	tests := []struct {
+		name   string
+		userID string
+		query  string
+	}{
+		{"bad user id", "walter", ""},
+		{"bad limit", uuid.NewString(), "?limit=zero"},
+		{"bad cursor", uuid.NewString(), "?after=nope"},
+		{"before", uuid.NewString(), "?before=" + cursor},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/"+tt.userID+"/followers"+tt.query, nil)
+		req.SetPathValue("userid", tt.userID)
+		rec := httptest.NewRecorder()
+
+		cfg.GetFollowersHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", tt.name, rec.Code)
+		}
+	}
+}
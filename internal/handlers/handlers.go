@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"sort"
 
 	"database/sql"
 
@@ -11,40 +10,96 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/realquiller/chirpy_server/internal/auth"
 	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/mailer"
+	"github.com/realquiller/chirpy_server/internal/moderation"
 )
 
 type ApiConfig struct {
 	FileserverHits atomic.Int32
 	DbQueries      *database.Queries
+	Db             *sql.DB
 	Platform       string
-	Secret         string
-	PolkaKey       string
+	Keys           *auth.KeySet
+	Mailer         mailer.Mailer
+	BaseURL        string
+	// RequireEmailVerification stops users posting until they've
+	// confirmed their email.
+	RequireEmailVerification bool
+	// PolkaSecrets verify webhook signatures. More than one is accepted
+	// while a secret is being rotated.
+	PolkaSecrets []string
+	// RedGracePeriod is how long Chirpy Red outlasts a missed renewal.
+	RedGracePeriod time.Duration
+	Profanity      *moderation.Filter
+	// WebhookClient sends outbound webhooks, nil means newWebhookClient
+	// with private addresses blocked outside of dev.
+	WebhookClient *http.Client
+
+	chirpWrites rateLimiter
 }
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Password     string    `json:"password"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	Email            string    `json:"email"`
+	EmailVerified    bool      `json:"email_verified"`
+	PendingEmail     string    `json:"pending_email,omitempty"`
+	Password         string    `json:"password"`
+	Token            string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	IsChirpyRed      bool      `json:"is_chirpy_red"`
+	Username         string    `json:"username"`
+	DisplayName      string    `json:"display_name"`
+	Bio              string    `json:"bio"`
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	LikeCount int64      `json:"like_count"`
+	LikedByMe bool       `json:"liked_by_me"`
+	Mentions  []Mention  `json:"mentions"`
+}
+
+func chirpFromDB(chirp database.Chirp) Chirp {
+	c := Chirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		Deleted:   chirp.DeletedAt.Valid,
+	}
+	if chirp.InReplyTo.Valid {
+		c.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	if chirp.EditedAt.Valid {
+		c.EditedAt = &chirp.EditedAt.Time
+	}
+	return c
+}
+
+// decorateChirps fills in the fields that live outside the chirps table,
+// batching each lookup across the whole slice.
+func (cfg *ApiConfig) decorateChirps(ctx context.Context, chirps []Chirp, viewer uuid.NullUUID) error {
+	if err := cfg.addLikeStats(ctx, chirps, viewer); err != nil {
+		return err
+	}
+	return cfg.addMentions(ctx, chirps)
 }
 
 func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
@@ -65,6 +120,25 @@ func (cfg *ApiConfig) MiddlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// MiddlewareAdmin only lets through requests made by admin users.
+func (cfg *ApiConfig) MiddlewareAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+		if err != nil || !user.IsAdmin {
+			respondWithError(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (cfg *ApiConfig) MetricsHandler(w http.ResponseWriter, r *http.Request) {
 	count := cfg.FileserverHits.Load()
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -104,8 +178,11 @@ func (cfg *ApiConfig) ResetHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *ApiConfig) NewUserHandler(w http.ResponseWriter, r *http.Request) {
 	type UserRequest struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		Password    string  `json:"password"`
+		Email       string  `json:"email"`
+		Username    *string `json:"username"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
 	}
 
 	var req UserRequest
@@ -117,6 +194,18 @@ func (cfg *ApiConfig) NewUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	email, err := validateEmail(req.Email)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	profile, err := validateProfile(req.Username, req.DisplayName, req.Bio)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	hashed_pw, err := auth.HashPassword(req.Password)
 
 	if err != nil {
@@ -126,22 +215,29 @@ func (cfg *ApiConfig) NewUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := cfg.DbQueries.CreateUser(context.Background(), database.CreateUserParams{
-		Email:          req.Email,
+		Email:          email,
 		HashedPassword: hashed_pw,
+		Username:       profile.Username,
+		DisplayName:    profile.DisplayName.String,
+		Bio:            profile.Bio.String,
 	})
 	if err != nil {
+		if isUniqueViolation(err, "users_username_lower_idx") {
+			respondWithError(w, "Username is already taken", http.StatusConflict)
+			return
+		}
+		if isUniqueViolation(err, "users_email_key") {
+			respondWithError(w, "Email is already in use", http.StatusConflict)
+			return
+		}
 		log.Printf("Error creating user: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	respondWithJSON(w, User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
-	}, http.StatusCreated)
+	cfg.sendEmailVerification(user.ID, user.Email)
+
+	respondWithJSON(w, userFromDB(user), http.StatusCreated)
 }
 
 func (cfg *ApiConfig) GetChirpsHandler(w http.ResponseWriter, r *http.Request) {
@@ -155,6 +251,8 @@ func (cfg *ApiConfig) GetChirpsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	authorID := uuid.NullUUID{}
+
 	if len(author_id) > 0 {
 		// parse the id into uuid
 		parsedID, err := uuid.Parse(author_id)
@@ -164,69 +262,56 @@ func (cfg *ApiConfig) GetChirpsHandler(w http.ResponseWriter, r *http.Request) {
 			respondWithError(w, "Invalid author id", http.StatusBadRequest)
 			return
 		}
-		chirps_author, err := cfg.DbQueries.GetChirpsByAuthor(r.Context(), parsedID)
-
-		if err != nil {
-			respondWithError(w, "Error getting chirps from GetChirpsByAuthor function", http.StatusInternalServerError)
-			return
-		}
-
-		chirps_author_list := []Chirp{}
-
-		for _, chirp := range chirps_author {
-			chirps_author_list = append(chirps_author_list, Chirp{
-				ID:        chirp.ID,
-				CreatedAt: chirp.CreatedAt,
-				UpdatedAt: chirp.UpdatedAt,
-				Body:      chirp.Body,
-				UserID:    chirp.UserID,
-			})
-		}
-
-		if sort_asc {
-			sort.Slice(chirps_author_list, func(i, j int) bool {
-				return chirps_author_list[i].CreatedAt.Before(chirps_author_list[j].CreatedAt)
-			})
-		} else {
-			sort.Slice(chirps_author_list, func(i, j int) bool {
-				return chirps_author_list[j].CreatedAt.Before(chirps_author_list[i].CreatedAt)
-			})
-		}
+		authorID = uuid.NullUUID{UUID: parsedID, Valid: true}
+	}
 
-		respondWithJSON(w, chirps_author_list, http.StatusOK)
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	chirps, err := cfg.DbQueries.GetChirps(r.Context())
+	// paging backwards walks the opposite direction from the cursor
+	backwards := page.Before != nil
+	cursor := page.After
+	if backwards {
+		cursor = page.Before
+	}
+	cursorCreatedAt, cursorID := cursorArgs(cursor)
+
+	var chirps []database.Chirp
+	if sort_asc != backwards {
+		chirps, err = cfg.DbQueries.GetChirpsAfter(r.Context(), database.GetChirpsAfterParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			RowLimit:        page.Limit + 1,
+		})
+	} else {
+		chirps, err = cfg.DbQueries.GetChirpsBefore(r.Context(), database.GetChirpsBeforeParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			RowLimit:        page.Limit + 1,
+		})
+	}
 	if err != nil {
-		respondWithError(w, "Error getting chirps from GetChirps function", http.StatusInternalServerError)
+		respondWithError(w, "Error getting chirps", http.StatusInternalServerError)
 		return
 	}
 
 	chirp_list := []Chirp{}
 
 	for _, chirp := range chirps {
-		chirp_list = append(chirp_list, Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		})
+		chirp_list = append(chirp_list, chirpFromDB(chirp))
 	}
 
-	if sort_asc {
-		sort.Slice(chirp_list, func(i, j int) bool {
-			return chirp_list[i].CreatedAt.Before(chirp_list[j].CreatedAt)
-		})
-	} else {
-		sort.Slice(chirp_list, func(i, j int) bool {
-			return chirp_list[j].CreatedAt.Before(chirp_list[i].CreatedAt)
-		})
+	if err := cfg.decorateChirps(r.Context(), chirp_list, cfg.viewerID(r.Context(), r.Header)); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
 	}
 
-	respondWithJSON(w, chirp_list, http.StatusOK)
-
+	respondWithJSON(w, newChirpPage(chirp_list, page.Limit, backwards, cursor != nil), http.StatusOK)
 }
 
 func (cfg *ApiConfig) GetChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -257,36 +342,27 @@ func (cfg *ApiConfig) GetChirpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 
 	}
-	respondWithJSON(w, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	}, http.StatusOK)
-}
-
-func (cfg *ApiConfig) ChirpHandler(w http.ResponseWriter, r *http.Request) {
-	type ChirpRequest struct {
-		Body string `json:"body"`
-	}
+	// deleted chirps still resolve so threads that point at them stay intact
+	chirp_list := []Chirp{chirpFromDB(chirp)}
 
-	// 1. Extract and validate token
-	tokenStr, err := auth.GetBearerToken(r.Header)
-
-	if err != nil {
-		respondWithError(w, "Error extracting token", http.StatusUnauthorized)
+	if err := cfg.decorateChirps(r.Context(), chirp_list, cfg.viewerID(r.Context(), r.Header)); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
 		return
 	}
 
-	if tokenStr == "" {
-		respondWithError(w, "Missing or malformed Authorization header", http.StatusUnauthorized)
-		return
+	respondWithJSON(w, chirp_list[0], http.StatusOK)
+}
+
+func (cfg *ApiConfig) ChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type ChirpRequest struct {
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
 	}
 
-	userID, err := auth.ValidateJWT(tokenStr, cfg.Secret)
+	// 1. Extract and validate token, bots can post with a chirps:write PAT
+	userID, err := cfg.ValidateScopedToken(r.Context(), r.Header, ScopeChirpsWrite)
 	if err != nil {
-		respondWithError(w, "Invalid token", http.StatusUnauthorized)
+		respondWithAuthError(w, err)
 		return
 	}
 
@@ -297,195 +373,229 @@ func (cfg *ApiConfig) ChirpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3. Create chirp in DB
-	chirp, err := cfg.DbQueries.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   chirpReq.Body,
-		UserID: userID,
-	})
+	// 3. Validate and clean the body, Chirpy Red members get more room
+	author, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, "Failed to create chirp", http.StatusInternalServerError)
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
 		return
 	}
 
-	// 4. Return only the required fields in expected format
-	respondWithJSON(w, Chirp{
-		ID:     chirp.ID,
-		Body:   chirp.Body,
-		UserID: chirp.UserID,
-	}, http.StatusCreated)
-}
-
-func (cfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	type LoginRequest struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+	if cfg.RequireEmailVerification && !author.EmailVerified {
+		respondWithError(w, "Verify your email address before posting", http.StatusForbidden)
+		return
 	}
 
-	// Decode JSON body
-	var login LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
-		respondWithError(w, "Error decoding JSON in LoginRequest", http.StatusInternalServerError)
+	body, verr := cfg.validateChirp(chirpReq.Body, author)
+	if verr != nil {
+		respondWithValidationError(w, verr)
 		return
 	}
 
-	user, err := cfg.DbQueries.GetUser(context.Background(), login.Email)
+	if wait, ok := cfg.allowChirpWrite(author); !ok {
+		respondWithRateLimit(w, wait)
+		return
+	}
 
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			respondWithError(w, "User not found", http.StatusNotFound)
+	// 4. Make sure the chirp being replied to exists
+	inReplyTo := uuid.NullUUID{}
+	if chirpReq.InReplyTo != nil {
+		parent, err := cfg.DbQueries.GetChirp(r.Context(), *chirpReq.InReplyTo)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				respondWithError(w, "Chirp being replied to not found", http.StatusNotFound)
+				return
+			}
+			respondWithError(w, "Error getting chirp being replied to", http.StatusInternalServerError)
 			return
-		} else {
-			respondWithError(w, "Error getting user from GetUser function", http.StatusInternalServerError)
+		}
+		if parent.DeletedAt.Valid {
+			respondWithError(w, "Can't reply to a deleted chirp", http.StatusBadRequest)
 			return
 		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
 	}
 
-	if err := auth.CheckPasswordHash(user.HashedPassword, login.Password); err != nil {
-		respondWithError(w, "Invalid email or password", http.StatusUnauthorized)
+	// 5. Create chirp in DB
+	chirp, err := cfg.createChirp(r.Context(), database.CreateChirpParams{
+		Body:      body,
+		UserID:    userID,
+		InReplyTo: inReplyTo,
+	})
+	if err != nil {
+		log.Printf("Error creating chirp: %v", err)
+		respondWithError(w, "Failed to create chirp", http.StatusInternalServerError)
 		return
 	}
 
-	token, err := auth.MakeJWT(user.ID, cfg.Secret, time.Duration(3600)*time.Second)
-	if err != nil {
-		respondWithError(w, "Error creating JWT", http.StatusInternalServerError)
+	// 6. Return the created chirp
+	chirp_list := []Chirp{chirpFromDB(chirp)}
+
+	if err := cfg.decorateChirps(r.Context(), chirp_list, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
 		return
 	}
 
-	refresh_token_id, err := auth.MakeRefreshToken()
+	respondWithJSON(w, chirp_list[0], http.StatusCreated)
+}
 
+// createChirp stores a chirp together with everything derived from its body
+// in a single transaction.
+func (cfg *ApiConfig) createChirp(ctx context.Context, params database.CreateChirpParams) (database.Chirp, error) {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
 	if err != nil {
-		respondWithError(w, "Error creating refresh token", http.StatusInternalServerError)
-		return
+		return database.Chirp{}, fmt.Errorf("error starting transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	refresh_token, err := cfg.DbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refresh_token_id,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(time.Hour * (24 * 60)),
-	})
-
+	chirp, err := insertChirp(ctx, cfg.DbQueries.WithTx(tx), params)
 	if err != nil {
-		respondWithError(w, "Error creating refresh token", http.StatusInternalServerError)
-		return
+		return database.Chirp{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.Chirp{}, fmt.Errorf("error committing chirp: %w", err)
 	}
-	respondWithJSON(w, User{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		Token:        token,
-		RefreshToken: refresh_token.Token,
-		IsChirpyRed:  user.IsChirpyRed,
-	}, http.StatusOK)
 
+	return chirp, nil
 }
 
-func (cfg *ApiConfig) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
-	type UpdateUserRequest struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+// insertChirp is createChirp for callers that already have a transaction.
+func insertChirp(ctx context.Context, qtx *database.Queries, params database.CreateChirpParams) (database.Chirp, error) {
+	chirp, err := qtx.CreateChirp(ctx, params)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("error creating chirp: %w", err)
 	}
 
-	// Decode JSON body
-	var update_user UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&update_user); err != nil {
-		respondWithError(w, "Error decoding JSON in LoginRequest", http.StatusInternalServerError)
-		return
+	if err := saveChirpBodyLinks(ctx, qtx, chirp); err != nil {
+		return database.Chirp{}, err
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil || token == "" {
-		respondWithError(w, "Token is missing", http.StatusUnauthorized)
-		return
+	err = enqueueWebhookEvent(ctx, qtx, WebhookEventChirpCreated, chirp.UserID, chirpEventDataFromDB(chirp))
+	if err != nil {
+		return database.Chirp{}, err
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.Secret)
+	return chirp, nil
+}
 
-	if err != nil {
-		respondWithError(w, "Invalid token", http.StatusUnauthorized)
-		return
+// saveChirpBodyLinks saves the hashtags and mentions found in the chirp's
+// body.
+func saveChirpBodyLinks(ctx context.Context, qtx *database.Queries, chirp database.Chirp) error {
+	if tags := extractHashtags(chirp.Body); len(tags) > 0 {
+		err := qtx.AddChirpTags(ctx, database.AddChirpTagsParams{
+			ChirpID: chirp.ID,
+			Tags:    tags,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving hashtags: %w", err)
+		}
 	}
 
-	hashed_pw, err := auth.HashPassword(update_user.Password)
+	return saveMentions(ctx, qtx, chirp)
+}
 
-	if err != nil {
-		respondWithError(w, "Couldn't hash a password", http.StatusInternalServerError)
-		return
+func (cfg *ApiConfig) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	type LoginRequest struct {
+		Password string `json:"password"`
+		Email    string `json:"email"`
 	}
 
-	err = cfg.DbQueries.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userID,
-		HashedPassword: hashed_pw,
-		Email:          update_user.Email,
-	})
-
-	if err != nil {
-		respondWithError(w, "Error updating user", http.StatusInternalServerError)
+	// Decode JSON body
+	var login LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		respondWithError(w, "Error decoding JSON in LoginRequest", http.StatusInternalServerError)
 		return
 	}
 
-	updated_user, err := cfg.DbQueries.GetUser(r.Context(), update_user.Email)
+	throttle := newLoginThrottle(login.Email, clientIP(r))
 
+	wait, err := cfg.lockedFor(r.Context(), throttle)
 	if err != nil {
-		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		respondWithError(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		respondWithLockout(w, wait)
 		return
 	}
 
-	respondWithJSON(w, User{
-		ID:          updated_user.ID,
-		CreatedAt:   updated_user.CreatedAt,
-		UpdatedAt:   updated_user.UpdatedAt,
-		Email:       updated_user.Email,
-		IsChirpyRed: updated_user.IsChirpyRed,
-	}, http.StatusOK)
-}
-
-func (cfg *ApiConfig) WebhookUpgradeUserHandler(w http.ResponseWriter, r *http.Request) {
-	api_key, err := auth.GetAPIKey(r.Header)
+	user, err := cfg.DbQueries.GetUser(context.Background(), login.Email)
 
 	if err != nil {
-		respondWithError(w, "Invalid API format", http.StatusUnauthorized)
-		return
+		// unknown emails get the same answer, and take as long, as a wrong
+		// password
+		if errors.Is(err, sql.ErrNoRows) {
+			checkDummyPassword(login.Password)
+			cfg.recordLoginFailure(r.Context(), throttle, uuid.Nil)
+			respondWithError(w, "Invalid email or password", http.StatusUnauthorized)
+			return
+		} else {
+			respondWithError(w, "Error getting user from GetUser function", http.StatusInternalServerError)
+			return
+		}
 	}
 
-	if api_key != cfg.PolkaKey {
-		respondWithError(w, "Invalid API key", http.StatusUnauthorized)
+	if err := auth.CheckPasswordHash(user.HashedPassword, login.Password); err != nil {
+		cfg.recordLoginFailure(r.Context(), throttle, user.ID)
+		respondWithError(w, "Invalid email or password", http.StatusUnauthorized)
 		return
 	}
 
-	type WebhookUpgrade struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+	// upgrade bcrypt and outdated argon2id hashes while we have the password
+	if auth.NeedsRehash(user.HashedPassword) {
+		cfg.rehashPassword(r.Context(), user.ID, login.Password)
 	}
 
-	var webhook WebhookUpgrade
-	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
-		respondWithError(w, "Error decoding JSON in WebhookUpgrade", http.StatusInternalServerError)
-		return
-	}
-
-	if webhook.Event != "user.upgraded" {
-		w.WriteHeader(http.StatusNoContent)
+	// with 2FA on, the password only earns a challenge token that has to be
+	// completed at /api/login/2fa, which clears the failures itself
+	if user.TotpEnabled {
+		challenge, err := cfg.Keys.MakeChallengeJWT(user.ID, twoFactorChallengeTTL)
+		if err != nil {
+			respondWithError(w, "Error creating challenge token", http.StatusInternalServerError)
+			return
+		}
+		respondWithJSON(w, TwoFactorChallenge{TwoFactorRequired: true, ChallengeToken: challenge}, http.StatusOK)
 		return
 	}
 
-	user_id, err := uuid.Parse(webhook.Data.UserID)
+	cfg.clearLoginFailures(r.Context(), throttle)
 
+	resp, err := cfg.startSession(r, user)
 	if err != nil {
-		respondWithError(w, "Invalid user ID format", http.StatusBadRequest)
+		respondWithError(w, "Error creating session", http.StatusInternalServerError)
 		return
 	}
+	respondWithJSON(w, resp, http.StatusOK)
 
-	err = cfg.DbQueries.UpgradeUser(r.Context(), user_id)
-	if err != nil {
-		respondWithError(w, "User not found", http.StatusNotFound)
+}
+
+// UpdateUserHandler serves the deprecated PUT /api/users. Older clients send
+// email and password with every update, so empty ones are left alone and a
+// password without current_password is taken as the current one: it's
+// checked, not changed. Changing the email or password otherwise works like
+// PATCH /api/users/me and needs current_password.
+func (cfg *ApiConfig) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", `</api/users/me>; rel="successor-version"`)
+
+	var req PatchUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if req.Email != nil && *req.Email == "" {
+		req.Email = nil
+	}
+	if req.Password != nil && *req.Password == "" {
+		req.Password = nil
+	}
+	if req.Password != nil && req.CurrentPassword == "" {
+		req.CurrentPassword = *req.Password
+		req.Password = nil
+	}
 
+	cfg.updateMe(w, r, req)
 }
 
 func (cfg *ApiConfig) DeleteChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -496,22 +606,15 @@ func (cfg *ApiConfig) DeleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil || token == "" {
-		respondWithError(w, "Token is missing", http.StatusUnauthorized)
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, cfg.Secret)
-
+	userID, err := cfg.ValidateScopedToken(r.Context(), r.Header, ScopeChirpsWrite)
 	if err != nil {
-		respondWithError(w, "Invalid token", http.StatusUnauthorized)
+		respondWithAuthError(w, err)
 		return
 	}
 
 	db_chirp, err := cfg.DbQueries.GetChirp(r.Context(), input_chirp)
 
-	if err != nil {
+	if err != nil || db_chirp.DeletedAt.Valid {
 		respondWithError(w, "Chirp wasn't found", http.StatusNotFound)
 		return
 	}
@@ -521,16 +624,62 @@ func (cfg *ApiConfig) DeleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = cfg.DbQueries.DeleteChirp(r.Context(), input_chirp)
+	has_replies, err := cfg.DbQueries.ChirpHasReplies(r.Context(), uuid.NullUUID{UUID: input_chirp, Valid: true})
+
+	if err != nil {
+		respondWithError(w, "Error checking for replies", http.StatusInternalServerError)
+		return
+	}
+
+	// keep a placeholder around when other chirps reply to this one
+	if has_replies {
+		err = cfg.softDeleteChirp(r.Context(), input_chirp)
+	} else {
+		err = cfg.DbQueries.DeleteChirp(r.Context(), input_chirp)
+	}
 
 	if err != nil {
 		respondWithError(w, "Error deleting chirp", http.StatusInternalServerError)
 		return
 	}
 
+	// the chirp is gone either way, a missed webhook isn't worth failing over
+	err = enqueueWebhookEvent(r.Context(), cfg.DbQueries, WebhookEventChirpDeleted, userID, chirpDeletedEventData{
+		ID:     input_chirp,
+		UserID: userID,
+	})
+	if err != nil {
+		log.Printf("Error queueing chirp.deleted webhook: %v", err)
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// softDeleteChirp blanks a chirp but keeps its row for the replies. Its
+// hashtags and mentions go with the body, so the placeholder doesn't give
+// away who it mentioned.
+func (cfg *ApiConfig) softDeleteChirp(ctx context.Context, chirpID uuid.UUID) error {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	if err := qtx.SoftDeleteChirp(ctx, chirpID); err != nil {
+		return fmt.Errorf("error deleting chirp: %w", err)
+	}
+	if err := qtx.DeleteChirpTags(ctx, chirpID); err != nil {
+		return fmt.Errorf("error removing hashtags: %w", err)
+	}
+	if err := qtx.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return fmt.Errorf("error removing mentions: %w", err)
+	}
+
+	return tx.Commit()
+}
+
 func (cfg *ApiConfig) RefreshHandler(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := cfg.ValidateRefreshToken(r.Context(), r.Header)
 	if err != nil {
@@ -538,13 +687,25 @@ func (cfg *ApiConfig) RefreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accToken, err := auth.MakeJWT(refreshToken.UserID, cfg.Secret, time.Hour)
+	newRefreshToken, err := cfg.rotateRefreshToken(r.Context(), refreshToken, r.UserAgent(), clientIP(r))
+	if errors.Is(err, errRefreshTokenReused) {
+		cfg.handleRefreshTokenReuse(r.Context(), refreshToken)
+		respondWithError(w, "unauthorized: token reuse detected", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Printf("Error rotating refresh token: %v", err)
+		respondWithError(w, "Error rotating refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	accToken, err := cfg.Keys.MakeSessionJWT(refreshToken.UserID, refreshToken.FamilyID, time.Hour)
 	if err != nil {
 		respondWithError(w, "Error creating JWT", http.StatusInternalServerError)
 		return
 	}
 
-	respondWithJSON(w, User{Token: accToken}, http.StatusOK)
+	respondWithJSON(w, User{Token: accToken, RefreshToken: newRefreshToken.Token}, http.StatusOK)
 
 }
 
@@ -586,6 +747,50 @@ func (cfg *ApiConfig) ValidateRefreshToken(ctx context.Context, headers http.Hea
 	return rt, nil
 }
 
+func (cfg *ApiConfig) ValidateAccessToken(ctx context.Context, headers http.Header) (uuid.UUID, error) {
+	userID, _, err := cfg.ValidateAccessTokenSession(ctx, headers)
+	return userID, err
+}
+
+// ValidateAccessTokenSession also returns the session the access token was
+// issued for. The token stops working as soon as its session is logged out
+// or revoked, rather than when it expires.
+func (cfg *ApiConfig) ValidateAccessTokenSession(ctx context.Context, headers http.Header) (uuid.UUID, uuid.UUID, error) {
+	token, err := auth.GetBearerToken(headers)
+	if err != nil || token == "" {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("unauthorized: invalid or missing bearer token")
+	}
+
+	userID, sessionID, err := cfg.Keys.ValidateSessionJWT(token)
+	if err != nil || sessionID == uuid.Nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("unauthorized: invalid token")
+	}
+
+	active, err := cfg.DbQueries.SessionIsActive(ctx, database.SessionIsActiveParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		log.Printf("Error checking session %v: %v", sessionID, err)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("unauthorized: couldn't check session")
+	}
+	if !active {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("unauthorized: session revoked")
+	}
+
+	return userID, sessionID, nil
+}
+
+// viewerID returns the caller's ID when a valid access token was sent.
+// Public endpoints use it to personalise responses without requiring auth.
+func (cfg *ApiConfig) viewerID(ctx context.Context, headers http.Header) uuid.NullUUID {
+	userID, err := cfg.ValidateAccessToken(ctx, headers)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
 func respondWithError(w http.ResponseWriter, msg string, code int) {
 	respondWithJSON(w, map[string]string{"error": msg}, code)
 }
@@ -604,21 +809,6 @@ func respondWithJSON(w http.ResponseWriter, data interface{}, code int) {
 	}
 }
 
-func filterProfanity(body string) string {
-	profanities := []string{"kerfuffle", "sharbert", "fornax"}
-	words := strings.Fields(body)
-
-	for i, word := range words {
-		normalized := strings.ToLower(word)
-		for _, profanity := range profanities {
-			if normalized == profanity {
-				words[i] = "****"
-			}
-		}
-	}
-	return strings.Join(words, " ")
-}
-
 // return func(s *State, cmd Command) error {
 // 	user, err := s.Db.GetUser(context.Background(), s.Config.CurrentUserName)
 // 	if err != nil {
@@ -0,0 +1,10 @@
+package handlers
+
+import "net/http"
+
+// JWKSHandler publishes the public keys our access tokens can be verified
+// with. Retiring keys stay listed until their tokens have expired.
+func (cfg *ApiConfig) JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, cfg.Keys.JWKS(), http.StatusOK)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+func (cfg *ApiConfig) LikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, true)
+}
+
+func (cfg *ApiConfig) UnlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setChirpLike(w, r, false)
+}
+
+// setChirpLike likes or unlikes a chirp. Both directions are idempotent, so
+// repeating a request is always safe.
+func (cfg *ApiConfig) setChirpLike(w http.ResponseWriter, r *http.Request, like bool) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	chirp, err := cfg.DbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, "Chirp not found", http.StatusNotFound)
+		return
+	}
+
+	if like {
+		err = cfg.DbQueries.LikeChirp(r.Context(), database.LikeChirpParams{
+			ChirpID: chirpID,
+			UserID:  userID,
+		})
+	} else {
+		err = cfg.DbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+			ChirpID: chirpID,
+			UserID:  userID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, "Error updating like", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addLikeStats fills in LikeCount and LikedByMe for all chirps at once.
+func (cfg *ApiConfig) addLikeStats(ctx context.Context, chirps []Chirp, viewer uuid.NullUUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	stats, err := cfg.DbQueries.GetChirpLikeStats(ctx, database.GetChirpLikeStatsParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	applyLikeStats(chirps, stats)
+	return nil
+}
+
+// applyLikeStats copies stats onto the chirps they belong to. Chirps without
+// a row keep a zero count.
+func applyLikeStats(chirps []Chirp, stats []database.GetChirpLikeStatsRow) {
+	byChirp := make(map[uuid.UUID]database.GetChirpLikeStatsRow, len(stats))
+	for _, s := range stats {
+		byChirp[s.ChirpID] = s
+	}
+
+	for i := range chirps {
+		s := byChirp[chirps[i].ID]
+		chirps[i].LikeCount = s.LikeCount
+		chirps[i].LikedByMe = s.LikedByMe
+	}
+}
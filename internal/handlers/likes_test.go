@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+func TestApplyLikeStats(t *testing.T) {
+	liked, unliked := Chirp{ID: uuid.New()}, Chirp{ID: uuid.New()}
+	chirps := []Chirp{liked, unliked}
+
+	applyLikeStats(chirps, []database.GetChirpLikeStatsRow{
+		{ChirpID: liked.ID, LikeCount: 3, LikedByMe: true},
+	})
+
+	if chirps[0].LikeCount != 3 || !chirps[0].LikedByMe {
+		t.Errorf("liked chirp got %d likes, liked by me %v", chirps[0].LikeCount, chirps[0].LikedByMe)
+	}
+	if chirps[1].LikeCount != 0 || chirps[1].LikedByMe {
+		t.Errorf("chirp without likes got %d likes, liked by me %v", chirps[1].LikeCount, chirps[1].LikedByMe)
+	}
+}
+
+func TestLikeChirpRequiresToken(t *testing.T) {
+	cfg := &ApiConfig{}
+
+	for _, handler := range []http.HandlerFunc{cfg.LikeChirpHandler, cfg.UnlikeChirpHandler} {
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps/x/likes", nil)
+		req.SetPathValue("chirpid", uuid.NewString())
+		rec := httptest.NewRecorder()
+
+		handler(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", rec.Code)
+		}
+	}
+}
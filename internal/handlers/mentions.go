@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// a mention must start the body or follow something that can't be part of a
+// handle or an email address
+var mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])(@([A-Za-z0-9_]{3,20}))\b`)
+
+// Mention is an @handle in a chirp body that resolved to a user. Start and
+// End are code point offsets into the body, End exclusive.
+type Mention struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Start    int32     `json:"start"`
+	End      int32     `json:"end"`
+}
+
+type mentionMatch struct {
+	Handle     string
+	Start, End int32
+}
+
+// extractMentions finds every @handle in body along with its position.
+func extractMentions(body string) []mentionMatch {
+	matches := []mentionMatch{}
+
+	for _, loc := range mentionRegex.FindAllStringSubmatchIndex(body, -1) {
+		start, end := loc[2], loc[3]
+		matches = append(matches, mentionMatch{
+			Handle: body[loc[4]:loc[5]],
+			Start:  int32(utf8.RuneCountInString(body[:start])),
+			End:    int32(utf8.RuneCountInString(body[:end])),
+		})
+	}
+
+	return matches
+}
+
+// saveMentions resolves the handles in a new chirp and stores the ones that
+// belong to real users. Unknown handles are left as plain text.
+func saveMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	matches := extractMentions(chirp.Body)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	handles := []string{}
+	for _, m := range matches {
+		handles = append(handles, strings.ToLower(m.Handle))
+	}
+
+	users, err := q.GetUsersByUsernames(ctx, handles)
+	if err != nil {
+		return fmt.Errorf("error resolving mentions: %w", err)
+	}
+
+	byHandle := map[string]uuid.UUID{}
+	for _, u := range users {
+		byHandle[strings.ToLower(u.Username.String)] = u.ID
+	}
+
+	params := database.AddChirpMentionsParams{ChirpID: chirp.ID}
+	for _, m := range matches {
+		userID, ok := byHandle[strings.ToLower(m.Handle)]
+		if !ok {
+			continue
+		}
+		params.UserIds = append(params.UserIds, userID)
+		params.StartOffsets = append(params.StartOffsets, m.Start)
+		params.EndOffsets = append(params.EndOffsets, m.End)
+	}
+
+	if len(params.UserIds) == 0 {
+		return nil
+	}
+
+	if err := q.AddChirpMentions(ctx, params); err != nil {
+		return fmt.Errorf("error saving mentions: %w", err)
+	}
+	return nil
+}
+
+// addMentions fills in Mentions for all chirps at once. Deleted chirps
+// never have any, whatever rows they left behind.
+func (cfg *ApiConfig) addMentions(ctx context.Context, chirps []Chirp) error {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if !chirp.Deleted {
+			ids = append(ids, chirp.ID)
+		}
+	}
+
+	byChirp := map[uuid.UUID][]Mention{}
+	if len(ids) > 0 {
+		rows, err := cfg.DbQueries.GetChirpMentions(ctx, ids)
+		if err != nil {
+			return err
+		}
+		byChirp = mentionsByChirp(rows)
+	}
+
+	for i := range chirps {
+		chirps[i].Mentions = byChirp[chirps[i].ID]
+		if chirps[i].Mentions == nil {
+			chirps[i].Mentions = []Mention{}
+		}
+	}
+	return nil
+}
+
+func mentionsByChirp(rows []database.GetChirpMentionsRow) map[uuid.UUID][]Mention {
+	byChirp := map[uuid.UUID][]Mention{}
+	for _, row := range rows {
+		byChirp[row.ChirpID] = append(byChirp[row.ChirpID], Mention{
+			UserID:   row.UserID,
+			Username: row.Username.String,
+			Start:    row.StartOffset,
+			End:      row.EndOffset,
+		})
+	}
+	return byChirp
+}
+
+func (cfg *ApiConfig) GetMyMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateScopedToken(r.Context(), r.Header, ScopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page.Before != nil {
+		respondWithError(w, "before is not supported here", http.StatusBadRequest)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(page.After)
+
+	chirps, err := cfg.DbQueries.GetMentionedChirps(r.Context(), database.GetMentionedChirpsParams{
+		UserID:          userID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, "Error getting mentions", http.StatusInternalServerError)
+		return
+	}
+
+	chirp_list := []Chirp{}
+	for _, chirp := range chirps {
+		chirp_list = append(chirp_list, chirpFromDB(chirp))
+	}
+
+	if err := cfg.decorateChirps(r.Context(), chirp_list, uuid.NullUUID{UUID: userID, Valid: true}); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, newChirpPage(chirp_list, page.Limit, false, false), http.StatusOK)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExtractMentions(t *testing.T) {
+	cases := map[string][]mentionMatch{
+		"hi @alice and @bob_99!": {
+			{Handle: "alice", Start: 3, End: 9},
+			{Handle: "bob_99", Start: 14, End: 21},
+		},
+		"@carol starts":             {{Handle: "carol", Start: 0, End: 6}},
+		"mail me@example.com or @x": {},
+		"héllo @dave":               {{Handle: "dave", Start: 6, End: 11}},
+	}
+
+	for body, want := range cases {
+		got := extractMentions(body)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("extractMentions(%q) = %+v, want %+v", body, got, want)
+		}
+	}
+}
+
+// Deleted placeholders don't reveal who they mentioned, and with nothing
+// else to look up the database isn't touched.
+func TestAddMentionsSkipsDeletedChirps(t *testing.T) {
+	cfg := &ApiConfig{}
+	chirps := []Chirp{{ID: uuid.New(), Deleted: true}}
+
+	if err := cfg.addMentions(context.Background(), chirps); err != nil {
+		t.Fatalf("addMentions: %v", err)
+	}
+	if chirps[0].Mentions == nil || len(chirps[0].Mentions) != 0 {
+		t.Errorf("deleted chirp got mentions %+v, want none", chirps[0].Mentions)
+	}
+}
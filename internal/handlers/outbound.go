@@ -0,0 +1,582 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// Events other services can subscribe to. Admins' subscriptions get every
+// event, everyone else's only those about themselves and their own chirps.
+const (
+	WebhookEventChirpCreated = "chirp.created"
+	WebhookEventChirpDeleted = "chirp.deleted"
+	WebhookEventUserUpgraded = "user.upgraded"
+)
+
+var webhookEventTypes = map[string]bool{
+	WebhookEventChirpCreated: true,
+	WebhookEventChirpDeleted: true,
+	WebhookEventUserUpgraded: true,
+}
+
+// Every delivery is signed the way Polka signs the webhooks it sends us, see
+// auth.SignWebhook, with the subscription's secret.
+const (
+	OutboundSignatureHeader = "X-Chirpy-Signature"
+	OutboundTimestampHeader = "X-Chirpy-Timestamp"
+	OutboundEventHeader     = "X-Chirpy-Event"
+	OutboundDeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+// A delivery is pending until the receiver answers with a 2xx, or until
+// maxWebhookAttempts have failed and it's given up on.
+const (
+	webhookDeliveryPending   = "pending"
+	webhookDeliveryDelivered = "delivered"
+	webhookDeliveryFailed    = "failed"
+)
+
+const (
+	maxWebhookAttempts   = 10
+	webhookBaseBackoff   = 30 * time.Second
+	webhookMaxBackoff    = 6 * time.Hour
+	webhookTimeout       = 10 * time.Second
+	webhookDeliveryBatch = 50
+
+	// a claimed delivery is retried after webhookLease if the server dies
+	// before recording how it went. A batch is sent one delivery at a time,
+	// so the lease has to outlast every one of them timing out.
+	webhookLease = webhookDeliveryBatch*webhookTimeout + time.Minute
+
+	maxWebhookSubscriptions = 10
+	minWebhookSecretLength  = 16
+	maxWebhookDeliveryLog   = 100
+)
+
+type WebhookSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+}
+
+func webhookSubscriptionFromDB(sub database.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:        sub.ID,
+		CreatedAt: sub.CreatedAt,
+		URL:       sub.Url,
+		Events:    sub.Events,
+	}
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	EventID        uuid.UUID  `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus *int32     `json:"response_status"`
+	Error          string     `json:"error,omitempty"`
+}
+
+func webhookDeliveryFromDB(delivery database.WebhookDelivery) WebhookDelivery {
+	d := WebhookDelivery{
+		ID:        delivery.ID,
+		CreatedAt: delivery.CreatedAt,
+		EventID:   delivery.EventID,
+		EventType: delivery.EventType,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		Error:     delivery.Error.String,
+	}
+	if delivery.Status == webhookDeliveryPending {
+		d.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.LastAttemptAt.Valid {
+		d.LastAttemptAt = &delivery.LastAttemptAt.Time
+	}
+	if delivery.ResponseStatus.Valid {
+		d.ResponseStatus = &delivery.ResponseStatus.Int32
+	}
+	return d
+}
+
+// webhookPayload is the body of every delivery. ID is the same for every
+// subscription the event goes to, so receivers can drop duplicates.
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// chirpEventData is the data of chirp.created.
+type chirpEventData struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+}
+
+func chirpEventDataFromDB(chirp database.Chirp) chirpEventData {
+	c := chirpEventData{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+	}
+	if chirp.InReplyTo.Valid {
+		c.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	return c
+}
+
+// chirpDeletedEventData is the data of chirp.deleted.
+type chirpDeletedEventData struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// userUpgradedEventData is the data of user.upgraded.
+type userUpgradedEventData struct {
+	UserID           uuid.UUID `json:"user_id"`
+	Plan             string    `json:"plan"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+}
+
+// enqueueWebhookEvent queues a delivery for every subscription that wants
+// the event. subject is the user the event is about. Pass a transaction's
+// queries and the deliveries only go out if it commits.
+func enqueueWebhookEvent(ctx context.Context, q *database.Queries, eventType string, subject uuid.UUID, data any) error {
+	eventID := uuid.New()
+	payload, err := json.Marshal(webhookPayload{
+		ID:        eventID,
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+
+	_, err = q.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		SubjectUserID: subject,
+	})
+	if err != nil {
+		return fmt.Errorf("error queueing webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// webhookBackoff is how long to wait after the nth failed attempt.
+func webhookBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 20 {
+		return webhookMaxBackoff
+	}
+
+	backoff := webhookBaseBackoff << (attempt - 1)
+	if backoff > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return backoff
+}
+
+var errWebhookAddressNotAllowed = errors.New("webhooks can't be sent to private addresses")
+
+// reservedPrefixes are special-purpose ranges netip has no method for.
+var reservedPrefixes = []netip.Prefix{
+	// "this network", which some stacks route to the local host
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// carrier-grade NAT, which some clouds use for their metadata services
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// IETF protocol assignments
+	netip.MustParsePrefix("192.0.0.0/24"),
+	// benchmarking, routed inside some networks
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// NAT64, which would reach any IPv4 address, private ones included,
+	// through the translator
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// isPublicAddr reports whether addr can belong to a receiver on the internet,
+// rather than to us, our network or a cloud metadata service.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsUnspecified() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsMulticast() &&
+		!isReservedAddr(addr)
+}
+
+func isReservedAddr(addr netip.Addr) bool {
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// dialPublicOnly is a net.Dialer Control hook. It runs on the resolved
+// address of every connection, so a hostname can't be pointed at a private
+// address after it was registered.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isPublicAddr(addr) {
+		return errWebhookAddressNotAllowed
+	}
+	return nil
+}
+
+// newWebhookClient doesn't follow redirects, a receiver has to answer at the
+// URL it registered. With publicOnly set it won't connect to private
+// addresses, and skips any proxy since that would connect on its behalf.
+func newWebhookClient(publicOnly bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if publicOnly {
+		dialer := &net.Dialer{Timeout: webhookTimeout, Control: dialPublicOnly}
+		transport.DialContext = dialer.DialContext
+		transport.Proxy = nil
+	}
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// deliverWebhook POSTs a delivery to its subscription. It returns the
+// response status, 0 if there wasn't one, and an error unless it was a 2xx.
+func deliverWebhook(ctx context.Context, client *http.Client, delivery database.ClaimWebhookDeliveriesRow, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(OutboundEventHeader, delivery.EventType)
+	req.Header.Set(OutboundDeliveryHeader, delivery.ID.String())
+	req.Header.Set(OutboundTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(OutboundSignatureHeader, auth.SignWebhook(delivery.Secret, now, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// DeliverWebhooks sends every delivery that's due and returns how many
+// were accepted by their receivers.
+func (cfg *ApiConfig) DeliverWebhooks(ctx context.Context) (int, error) {
+	client := cfg.WebhookClient
+	if client == nil {
+		// dev receivers are usually running on localhost
+		client = newWebhookClient(cfg.Platform != "dev")
+	}
+
+	delivered := 0
+	for {
+		due, err := cfg.DbQueries.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+			LeaseSeconds: webhookLease.Seconds(),
+			RowLimit:     webhookDeliveryBatch,
+		})
+		if err != nil {
+			return delivered, fmt.Errorf("error claiming webhook deliveries: %w", err)
+		}
+
+		for _, delivery := range due {
+			now := time.Now()
+			status, sendErr := deliverWebhook(ctx, client, delivery, now)
+
+			attempt := database.RecordWebhookDeliveryAttemptParams{
+				ID:             delivery.ID,
+				Status:         webhookDeliveryDelivered,
+				NextAttemptAt:  delivery.NextAttemptAt,
+				ResponseStatus: sql.NullInt32{Int32: int32(status), Valid: status != 0},
+			}
+			if sendErr == nil {
+				delivered++
+			} else {
+				attempts := int(delivery.Attempts) + 1
+				attempt.Status = webhookDeliveryPending
+				attempt.NextAttemptAt = now.Add(webhookBackoff(attempts))
+				attempt.Error = sql.NullString{String: sendErr.Error(), Valid: true}
+				if attempts >= maxWebhookAttempts {
+					attempt.Status = webhookDeliveryFailed
+				}
+			}
+
+			if err := cfg.DbQueries.RecordWebhookDeliveryAttempt(ctx, attempt); err != nil {
+				return delivered, fmt.Errorf("error recording webhook delivery: %w", err)
+			}
+		}
+
+		if len(due) < webhookDeliveryBatch {
+			return delivered, nil
+		}
+	}
+}
+
+// RunWebhookDispatcher calls DeliverWebhooks every interval until ctx is
+// done.
+func (cfg *ApiConfig) RunWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := cfg.DeliverWebhooks(ctx); err != nil {
+			log.Printf("Error delivering webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// validateWebhookURL only allows plain http and private addresses outside
+// of production. The address check here only catches IP literals early,
+// hostnames are checked when each delivery connects, see dialPublicOnly.
+func (cfg *ApiConfig) validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return fmt.Errorf("url must be an absolute URL")
+	}
+	if cfg.Platform == "dev" {
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return fmt.Errorf("url must use https")
+		}
+		return nil
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("url must use https")
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !isPublicAddr(addr) {
+		return fmt.Errorf("url can't point at a private address")
+	}
+	return nil
+}
+
+func (cfg *ApiConfig) CreateWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	type SubscriptionRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret"`
+	}
+
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req SubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := cfg.validateWebhookURL(req.URL); err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Events) == 0 {
+		respondWithError(w, "At least one event is required", http.StatusBadRequest)
+		return
+	}
+	events := []string{}
+	seen := map[string]bool{}
+	for _, event := range req.Events {
+		if !webhookEventTypes[event] {
+			respondWithError(w, fmt.Sprintf("Unknown event %q", event), http.StatusBadRequest)
+			return
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+
+	// without a secret of their own the receiver gets one generated
+	secret := req.Secret
+	if secret == "" {
+		secret, err = auth.MakeToken()
+		if err != nil {
+			respondWithError(w, "Error creating secret", http.StatusInternalServerError)
+			return
+		}
+	} else if len(secret) < minWebhookSecretLength {
+		respondWithError(w, fmt.Sprintf("Secret must be at least %d characters", minWebhookSecretLength), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := cfg.DbQueries.ListWebhookSubscriptions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting webhook subscriptions", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxWebhookSubscriptions {
+		respondWithError(w, fmt.Sprintf("At most %d webhook subscriptions are allowed", maxWebhookSubscriptions), http.StatusConflict)
+		return
+	}
+
+	sub, err := cfg.DbQueries.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		UserID: userID,
+		Url:    req.URL,
+		Events: events,
+		Secret: secret,
+	})
+	if err != nil {
+		respondWithError(w, "Error saving webhook subscription", http.StatusInternalServerError)
+		return
+	}
+
+	resp := webhookSubscriptionFromDB(sub)
+	resp.Secret = secret
+	respondWithJSON(w, resp, http.StatusCreated)
+}
+
+func (cfg *ApiConfig) ListWebhookSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := cfg.DbQueries.ListWebhookSubscriptions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting webhook subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	subs := []WebhookSubscription{}
+	for _, row := range rows {
+		subs = append(subs, webhookSubscriptionFromDB(row))
+	}
+
+	respondWithJSON(w, subs, http.StatusOK)
+}
+
+func (cfg *ApiConfig) DeleteWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	subID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, "Invalid subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := cfg.DbQueries.DeleteWebhookSubscription(r.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:     subID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, "Failed to delete webhook subscription", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, "Webhook subscription not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveriesHandler is the delivery log of one subscription,
+// newest first.
+func (cfg *ApiConfig) ListWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	subID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, "Invalid subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	_, err = cfg.DbQueries.GetWebhookSubscription(r.Context(), database.GetWebhookSubscriptionParams{
+		ID:     subID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Webhook subscription not found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Error getting webhook subscription", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := cfg.DbQueries.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		SubscriptionID: subID,
+		Limit:          maxWebhookDeliveryLog,
+	})
+	if err != nil {
+		respondWithError(w, "Error getting webhook deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, row := range rows {
+		deliveries = append(deliveries, webhookDeliveryFromDB(row))
+	}
+
+	respondWithJSON(w, deliveries, http.StatusOK)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// tokenPage is a landing page for a link mailed out with a token. Following
+// the link only shows a form; the token is used up when the form is posted
+// to Endpoint, so mail scanners that fetch links don't spend it.
+type tokenPage struct {
+	Title    string
+	Endpoint string
+	Token    string
+	// Password asks for a new password to send along with the token.
+	Password bool
+	Button   string
+	Done     string
+}
+
+var tokenPageTemplate = template.Must(template.New("token").Parse(`<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}} - Chirpy</title>
+</head>
+<body>
+  <h1>{{.Title}}</h1>
+  <form id="form">
+    {{- if .Password}}
+    <label>New password <input type="password" name="password" autocomplete="new-password" required></label>
+    {{- end}}
+    <button type="submit">{{.Button}}</button>
+  </form>
+  <p id="message"></p>
+  <script>
+    const form = document.getElementById("form");
+    const message = document.getElementById("message");
+    form.addEventListener("submit", async (event) => {
+      event.preventDefault();
+      const body = {token: {{.Token}}};
+      if (form.password) {
+        body.password = form.password.value;
+      }
+      const res = await fetch({{.Endpoint}}, {
+        method: "POST",
+        headers: {"Content-Type": "application/json"},
+        body: JSON.stringify(body),
+      });
+      if (res.ok) {
+        form.hidden = true;
+        message.textContent = {{.Done}};
+        return;
+      }
+      const data = await res.json().catch(() => ({}));
+      message.textContent = data.error || "Something went wrong, please try again.";
+    });
+  </script>
+</body>
+</html>
+`))
+
+// renderTokenPage serves page for the token in the link's query string.
+func renderTokenPage(w http.ResponseWriter, r *http.Request, page tokenPage) {
+	page.Token = r.URL.Query().Get("token")
+	if page.Token == "" {
+		http.Error(w, "This link is missing its token.", http.StatusBadRequest)
+		return
+	}
+
+	// the token is in the URL, keep it out of caches and Referer headers
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := tokenPageTemplate.Execute(w, page); err != nil {
+		log.Printf("Error rendering %s page: %v", page.Title, err)
+	}
+}
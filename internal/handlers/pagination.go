@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// ChirpPage is the envelope returned by every paginated chirp listing.
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+// pageCursor is the keyset position of a row. Clients only ever see it
+// base64 encoded, so its shape can change without breaking them.
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	// Rank is only set by ranked listings such as search.
+	Rank *float32 `json:"r,omitempty"`
+}
+
+type pageRequest struct {
+	Limit int32
+	After *pageCursor
+	// Before is set when the client is paging backwards.
+	Before *pageCursor
+}
+
+func encodeCursor(c pageCursor) string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("invalid cursor")
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil || c.CreatedAt.IsZero() {
+		return c, fmt.Errorf("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// parsePageRequest reads the limit, after and before query parameters.
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	query := r.URL.Query()
+	page := pageRequest{Limit: defaultPageLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return page, fmt.Errorf("invalid limit")
+		}
+		if n > maxPageLimit {
+			n = maxPageLimit
+		}
+		page.Limit = int32(n)
+	}
+
+	after, before := query.Get("after"), query.Get("before")
+	if after != "" && before != "" {
+		return page, fmt.Errorf("after and before can't be used together")
+	}
+
+	if after != "" {
+		c, err := decodeCursor(after)
+		if err != nil {
+			return page, err
+		}
+		page.After = &c
+	}
+
+	if before != "" {
+		c, err := decodeCursor(before)
+		if err != nil {
+			return page, err
+		}
+		page.Before = &c
+	}
+
+	return page, nil
+}
+
+// cursorArgs turns an optional cursor into the nullable query parameters
+// the keyset queries expect.
+func cursorArgs(c *pageCursor) (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+// pageRows trims rows fetched with limit+1 and puts them in display order;
+// when backwards is set they arrived in reverse. It also reports whether the
+// page gets a next and a previous cursor.
+func pageRows[T any](rows []T, limit int32, backwards bool, hasCursor bool) ([]T, bool, bool) {
+	hasMore := len(rows) > int(limit)
+	if hasMore {
+		rows = rows[:limit]
+	}
+
+	if backwards {
+		slices.Reverse(rows)
+	}
+
+	if len(rows) == 0 {
+		return rows, false, false
+	}
+	if backwards {
+		return rows, true, hasMore
+	}
+	return rows, hasMore, hasCursor
+}
+
+// newChirpPage builds the response for rows fetched with limit+1. When
+// backwards is set the rows arrived in reverse display order.
+func newChirpPage(chirps []Chirp, limit int32, backwards bool, hasCursor bool) ChirpPage {
+	chirps, hasNext, hasPrev := pageRows(chirps, limit, backwards, hasCursor)
+
+	page := ChirpPage{Chirps: chirps}
+	if hasNext {
+		last := chirps[len(chirps)-1]
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	if hasPrev {
+		first := chirps[0]
+		page.PrevCursor = encodeCursor(pageCursor{CreatedAt: first.CreatedAt, ID: first.ID})
+	}
+	return page
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := pageCursor{CreatedAt: time.Date(2025, 4, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("error decoding cursor: %v", err)
+	}
+
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("expected cursor %+v, got %+v", want, got)
+	}
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	for _, s := range []string{"", "not-base64!", "e30"} {
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("expected error for cursor %q, got none", s)
+		}
+	}
+}
+
+func TestNewChirpPage(t *testing.T) {
+	base := time.Now()
+	chirps := make([]Chirp, 4)
+	for i := range chirps {
+		chirps[i] = Chirp{ID: uuid.New(), CreatedAt: base.Add(time.Duration(i) * time.Second)}
+	}
+
+	page := newChirpPage(append([]Chirp{}, chirps...), 3, false, false)
+	if len(page.Chirps) != 3 {
+		t.Fatalf("expected 3 chirps, got %d", len(page.Chirps))
+	}
+	if page.NextCursor == "" || page.PrevCursor != "" {
+		t.Errorf("expected only a next cursor, got next=%q prev=%q", page.NextCursor, page.PrevCursor)
+	}
+
+	next, _ := decodeCursor(page.NextCursor)
+	if next.ID != chirps[2].ID {
+		t.Errorf("expected next cursor to point at %v, got %v", chirps[2].ID, next.ID)
+	}
+
+	// rows fetched backwards come newest first and must be flipped
+	reversed := []Chirp{chirps[3], chirps[2], chirps[1]}
+	page = newChirpPage(reversed, 3, true, true)
+	if page.Chirps[0].ID != chirps[1].ID || page.Chirps[2].ID != chirps[3].ID {
+		t.Errorf("expected backwards page in display order")
+	}
+	if page.PrevCursor != "" || page.NextCursor == "" {
+		t.Errorf("expected only a next cursor, got next=%q prev=%q", page.NextCursor, page.PrevCursor)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/mailer"
+)
+
+const passwordResetTTL = time.Hour
+
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetRequestHandler mails a reset link if the email belongs to a
+// user. It answers the same way either way, and does the work in the
+// background so the response time doesn't give it away either.
+func (cfg *ApiConfig) PasswordResetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	type ResetRequest struct {
+		Email string `json:"email"`
+	}
+
+	var req ResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := cfg.sendPasswordReset(ctx, req.Email); err != nil {
+			log.Printf("Error sending password reset: %v", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *ApiConfig) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := cfg.DbQueries.GetUser(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	token, err := auth.MakeToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.DbQueries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving reset token: %w", err)
+	}
+
+	link := cfg.BaseURL + "/reset-password?token=" + url.QueryEscape(token)
+
+	return cfg.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"To choose a new password, open this link within the next hour:\n\n%s\n\n"+
+			"If it wasn't you, you can ignore this email.\n", link),
+	})
+}
+
+// PasswordResetPageHandler is where the mailed reset link lands. It asks for
+// the new password and posts it to PasswordResetConfirmHandler.
+func (cfg *ApiConfig) PasswordResetPageHandler(w http.ResponseWriter, r *http.Request) {
+	renderTokenPage(w, r, tokenPage{
+		Title:    "Reset your password",
+		Endpoint: "/api/password-reset/confirm",
+		Password: true,
+		Button:   "Set password",
+		Done:     "Your password has been changed. Log in with the new one.",
+	})
+}
+
+// PasswordResetConfirmHandler sets a new password. Every session is logged
+// out, since whoever forgot the password may not be the only one holding them.
+func (cfg *ApiConfig) PasswordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	type ConfirmRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var req ConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Password == "" {
+		respondWithError(w, "Password is required", http.StatusBadRequest)
+		return
+	}
+
+	hashed, err := auth.HashPassword(req.Password)
+	if err != nil {
+		respondWithError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := cfg.resetPassword(r.Context(), req.Token, hashed); err != nil {
+		if errors.Is(err, errInvalidResetToken) {
+			respondWithError(w, "Invalid or expired reset token", http.StatusBadRequest)
+			return
+		}
+		respondWithError(w, "Error resetting password", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetPassword uses up token and every other outstanding reset token of the
+// same user, so an older link mailed earlier can't be used afterwards.
+func (cfg *ApiConfig) resetPassword(ctx context.Context, token, hashedPassword string) error {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	userID, err := qtx.UsePasswordResetToken(ctx, auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return errInvalidResetToken
+	}
+	if err != nil {
+		return fmt.Errorf("error using reset token: %w", err)
+	}
+
+	err = qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating password: %w", err)
+	}
+
+	if err := qtx.ExpirePasswordResetTokens(ctx, userID); err != nil {
+		return fmt.Errorf("error expiring reset tokens: %w", err)
+	}
+
+	if err := qtx.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+
+	err = qtx.CreateSecurityEvent(ctx, database.CreateSecurityEventParams{
+		UserID:    userID,
+		EventType: securityEventPasswordReset,
+		Details:   "password reset by email; all sessions revoked",
+	})
+	if err != nil {
+		return fmt.Errorf("error recording security event: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// rehashPassword stores password hashed with the current algorithm and
+// parameters. It's best effort, the login goes ahead if it fails.
+func (cfg *ApiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("Error rehashing password for user %v: %v", userID, err)
+		return
+	}
+
+	err = cfg.DbQueries.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashed,
+	})
+	if err != nil {
+		log.Printf("Error saving rehashed password for user %v: %v", userID, err)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/mailer"
+)
+
+// TestPasswordResetFlow follows a reset from the email to logging in with
+// the new password: the mailed link opens the reset page, and posting its
+// token sets the password, logs out every session and uses the token up.
+func TestPasswordResetFlow(t *testing.T) {
+	userID := uuid.New()
+	email := "walt@breakingbad.com"
+	hashed, err := auth.HashPassword("heisenberg")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	type resetToken struct {
+		userID    string
+		expiresAt time.Time
+		used      bool
+	}
+	tokens := map[string]*resetToken{}
+	sessionsRevoked := false
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"GetUser": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[0] != email {
+				return nil, nil
+			}
+			return [][]driver.Value{fakeUserRow(database.User{ID: userID, Email: email, HashedPassword: hashed})}, nil
+		},
+		"CreatePasswordResetToken": func(args []driver.Value) ([][]driver.Value, error) {
+			tokens[args[0].(string)] = &resetToken{userID: args[1].(string), expiresAt: args[2].(time.Time)}
+			return nil, nil
+		},
+		"UsePasswordResetToken": func(args []driver.Value) ([][]driver.Value, error) {
+			token := tokens[args[0].(string)]
+			if token == nil || token.used || time.Now().After(token.expiresAt) {
+				return nil, nil
+			}
+			token.used = true
+			return [][]driver.Value{{token.userID}}, nil
+		},
+		"UpdateUserPassword": func(args []driver.Value) ([][]driver.Value, error) {
+			hashed = args[1].(string)
+			return nil, nil
+		},
+		"ExpirePasswordResetTokens": func(args []driver.Value) ([][]driver.Value, error) {
+			for _, token := range tokens {
+				if token.userID == args[0] {
+					token.used = true
+				}
+			}
+			return nil, nil
+		},
+		"RevokeUserRefreshTokens": func(args []driver.Value) ([][]driver.Value, error) {
+			sessionsRevoked = true
+			return nil, nil
+		},
+		"CreateSecurityEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+	})
+
+	mail := mailer.NewMemoryMailer()
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Mailer: mail, BaseURL: "https://chirpy.example.com"}
+
+	if err := cfg.sendPasswordReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("sendPasswordReset for an unknown email: %v", err)
+	}
+	if err := cfg.sendPasswordReset(context.Background(), email); err != nil {
+		t.Fatalf("sendPasswordReset: %v", err)
+	}
+
+	messages := mail.Messages()
+	if len(messages) != 1 || messages[0].To != email {
+		t.Fatalf("expected one email to %s, got %+v", email, messages)
+	}
+
+	link, err := url.Parse(regexp.MustCompile(`https?://\S+`).FindString(messages[0].Body))
+	if err != nil || link.Path != "/reset-password" {
+		t.Fatalf("email doesn't link to /reset-password: %q", messages[0].Body)
+	}
+	token := link.Query().Get("token")
+
+	// following the link shows the form without using the token
+	rec := httptest.NewRecorder()
+	cfg.PasswordResetPageHandler(rec, httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), token) {
+		t.Fatalf("reset page: status %d, token in page %v", rec.Code, strings.Contains(rec.Body.String(), token))
+	}
+	if got := rec.Header().Get("Referrer-Policy"); got != "no-referrer" {
+		t.Errorf("reset page Referrer-Policy = %q, want no-referrer", got)
+	}
+
+	confirm := func(token string) int {
+		body := `{"token":"` + token + `","password":"say-my-name"}`
+		rec := httptest.NewRecorder()
+		cfg.PasswordResetConfirmHandler(rec, httptest.NewRequest(http.MethodPost, "/api/password-reset/confirm", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	if code := confirm(token); code != http.StatusNoContent {
+		t.Fatalf("confirm: status %d, want 204", code)
+	}
+	if err := auth.CheckPasswordHash(hashed, "say-my-name"); err != nil {
+		t.Errorf("new password doesn't work: %v", err)
+	}
+	if !sessionsRevoked {
+		t.Errorf("sessions weren't revoked")
+	}
+
+	if code := confirm(token); code != http.StatusBadRequest {
+		t.Errorf("reusing the token: status %d, want 400", code)
+	}
+}
+
+func TestPasswordResetPageNeedsToken(t *testing.T) {
+	cfg := &ApiConfig{}
+	rec := httptest.NewRecorder()
+	cfg.PasswordResetPageHandler(rec, httptest.NewRequest(http.MethodGet, "/reset-password", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// How many chirps a user can post or edit per chirpRateWindow. Chirpy Red
+// members get the higher limit.
+const (
+	chirpRateWindow   = time.Minute
+	chirpRateLimit    = 10
+	redChirpRateLimit = 60
+)
+
+// rateLimiter counts actions per user in fixed windows. The zero value is
+// ready to use. Counts live in memory, so each server instance keeps its own.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[uuid.UUID]rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// allow records an action by userID if it's within limit per window.
+// Otherwise it returns how long until the next window starts.
+func (rl *rateLimiter) allow(userID uuid.UUID, limit int, window time.Duration, now time.Time) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.windows == nil {
+		rl.windows = map[uuid.UUID]rateWindow{}
+	}
+
+	// forget users whose window is over, so the map doesn't keep growing
+	if len(rl.windows) > 10000 {
+		for id, w := range rl.windows {
+			if now.Sub(w.start) >= window {
+				delete(rl.windows, id)
+			}
+		}
+	}
+
+	w, ok := rl.windows[userID]
+	if !ok || now.Sub(w.start) >= window {
+		w = rateWindow{start: now}
+	}
+
+	if w.count >= limit {
+		return w.start.Add(window).Sub(now), false
+	}
+
+	w.count++
+	rl.windows[userID] = w
+	return 0, true
+}
+
+func chirpRateLimitFor(user database.User) int {
+	if hasFeature(user, FeatureHigherRateLimits) {
+		return redChirpRateLimit
+	}
+	return chirpRateLimit
+}
+
+// allowChirpWrite counts a chirp posted, edited or scheduled by user against
+// their plan's rate limit.
+func (cfg *ApiConfig) allowChirpWrite(user database.User) (time.Duration, bool) {
+	return cfg.chirpWrites.allow(user.ID, chirpRateLimitFor(user), chirpRateWindow, time.Now())
+}
+
+func respondWithRateLimit(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, "Rate limit exceeded, try again later", http.StatusTooManyRequests)
+}
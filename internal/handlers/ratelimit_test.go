@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var rl rateLimiter
+	user := uuid.New()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if _, ok := rl.allow(user, 3, time.Minute, now.Add(time.Duration(i)*time.Second)); !ok {
+			t.Fatalf("action %d was limited", i+1)
+		}
+	}
+
+	wait, ok := rl.allow(user, 3, time.Minute, now.Add(20*time.Second))
+	if ok {
+		t.Fatalf("action over the limit was allowed")
+	}
+	if wait != 40*time.Second {
+		t.Errorf("wait = %v, want 40s", wait)
+	}
+
+	if _, ok := rl.allow(uuid.New(), 3, time.Minute, now.Add(20*time.Second)); !ok {
+		t.Errorf("another user was limited")
+	}
+
+	if _, ok := rl.allow(user, 3, time.Minute, now.Add(time.Minute)); !ok {
+		t.Errorf("action in the next window was limited")
+	}
+}
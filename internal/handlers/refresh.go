@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const (
+	securityEventRefreshTokenReuse = "refresh_token_reuse"
+	securityEventPasswordReset     = "password_reset"
+	securityEventPasswordChanged   = "password_changed"
+)
+
+var errRefreshTokenReused = errors.New("refresh token was already rotated")
+
+// rotateRefreshToken retires old and issues its successor in the same token
+// family. The successor keeps the family's original expiry, so rotation never
+// extends a session past 60 days.
+func (cfg *ApiConfig) rotateRefreshToken(ctx context.Context, old database.RefreshToken, userAgent, ip string) (database.RefreshToken, error) {
+	if old.RotatedAt.Valid {
+		return database.RefreshToken{}, errRefreshTokenReused
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.RefreshToken{}, err
+	}
+
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.RefreshToken{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	// only one caller can win the rotation, anyone else is replaying it
+	rotated, err := qtx.RotateRefreshToken(ctx, old.Token)
+	if err != nil {
+		return database.RefreshToken{}, fmt.Errorf("error rotating refresh token: %w", err)
+	}
+	if rotated == 0 {
+		return database.RefreshToken{}, errRefreshTokenReused
+	}
+
+	next, err := qtx.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:       token,
+		UserID:      old.UserID,
+		ExpiresAt:   old.ExpiresAt,
+		FamilyID:    old.FamilyID,
+		ParentToken: sql.NullString{String: old.Token, Valid: true},
+		UserAgent:   userAgent,
+		IpAddress:   ip,
+	})
+	if err != nil {
+		return database.RefreshToken{}, fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.RefreshToken{}, fmt.Errorf("error committing rotation: %w", err)
+	}
+
+	return next, nil
+}
+
+// handleRefreshTokenReuse runs when a rotated token shows up again. Either
+// the legitimate client or an attacker holds a stolen copy, and we can't tell
+// which, so the whole family is logged out.
+func (cfg *ApiConfig) handleRefreshTokenReuse(ctx context.Context, token database.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user %v, revoking family %v", token.UserID, token.FamilyID)
+
+	if err := cfg.DbQueries.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		log.Printf("Error revoking refresh token family %v: %v", token.FamilyID, err)
+	}
+
+	err := cfg.DbQueries.CreateSecurityEvent(ctx, database.CreateSecurityEventParams{
+		UserID:    token.UserID,
+		EventType: securityEventRefreshTokenReuse,
+		Details:   fmt.Sprintf("rotated refresh token presented again; token family %v revoked", token.FamilyID),
+	})
+	if err != nil {
+		log.Printf("Error recording security event: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+)
+
+// fakeRefreshToken is a refresh_tokens row kept by the refresh tests.
+type fakeRefreshToken struct {
+	token     string
+	userID    string
+	familyID  string
+	expiresAt time.Time
+	revokedAt any
+	rotatedAt any
+}
+
+func (rt *fakeRefreshToken) row() []driver.Value {
+	now := time.Now()
+	return []driver.Value{
+		rt.token, now, now, rt.userID, rt.expiresAt, rt.revokedAt, rt.familyID, nil,
+		rt.rotatedAt, "test", "127.0.0.1", now,
+	}
+}
+
+// TestRefreshTokenRotation refreshes once, then replays the token that was
+// just rotated away. The replay is rejected, logs out the whole family,
+// including the token the first refresh handed out, and leaves a security
+// event behind.
+func TestRefreshTokenRotation(t *testing.T) {
+	userID := uuid.New().String()
+	familyID := uuid.New().String()
+
+	tokens := map[string]*fakeRefreshToken{
+		"first": {token: "first", userID: userID, familyID: familyID, expiresAt: time.Now().Add(time.Hour)},
+	}
+	var events []string
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"GetRefreshToken": func(args []driver.Value) ([][]driver.Value, error) {
+			rt := tokens[args[0].(string)]
+			if rt == nil {
+				return nil, nil
+			}
+			return [][]driver.Value{rt.row()}, nil
+		},
+		"RotateRefreshToken": func(args []driver.Value) ([][]driver.Value, error) {
+			rt := tokens[args[0].(string)]
+			if rt == nil || rt.rotatedAt != nil || rt.revokedAt != nil {
+				return nil, nil
+			}
+			rt.rotatedAt = time.Now()
+			return [][]driver.Value{{}}, nil
+		},
+		"CreateRefreshToken": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[4] != "first" {
+				t.Errorf("successor has parent %v, want first", args[4])
+			}
+			rt := &fakeRefreshToken{
+				token:     args[0].(string),
+				userID:    args[1].(string),
+				expiresAt: args[2].(time.Time),
+				familyID:  args[3].(string),
+			}
+			tokens[rt.token] = rt
+			return [][]driver.Value{rt.row()}, nil
+		},
+		"RevokeRefreshTokenFamily": func(args []driver.Value) ([][]driver.Value, error) {
+			for _, rt := range tokens {
+				if rt.familyID == args[0] && rt.revokedAt == nil {
+					rt.revokedAt = time.Now()
+				}
+			}
+			return nil, nil
+		},
+		"CreateSecurityEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[0] != userID {
+				t.Errorf("security event recorded for %v, want %v", args[0], userID)
+			}
+			events = append(events, args[1].(string))
+			return nil, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Keys: auth.NewHMACKeySet("test-secret")}
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		cfg.RefreshHandler(rec, req)
+		return rec
+	}
+
+	rec := refresh("first")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh returned %d: %s", rec.Code, rec.Body)
+	}
+	var resp User
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("error decoding refresh response: %v", err)
+	}
+	second := resp.RefreshToken
+	if second == "" || second == "first" {
+		t.Fatalf("refresh handed out %q, want a new token", second)
+	}
+	if tokens[second].familyID != familyID {
+		t.Errorf("successor is in family %v, want %v", tokens[second].familyID, familyID)
+	}
+
+	if rec := refresh("first"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replaying a rotated token returned %d, want 401", rec.Code)
+	}
+	if len(events) != 1 || events[0] != securityEventRefreshTokenReuse {
+		t.Errorf("security events = %v, want [%s]", events, securityEventRefreshTokenReuse)
+	}
+
+	// the replay took the legitimate successor down with it
+	if rec := refresh(second); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refreshing with the revoked successor returned %d, want 401", rec.Code)
+	}
+	if len(events) != 1 {
+		t.Errorf("a revoked token recorded another security event: %v", events)
+	}
+}
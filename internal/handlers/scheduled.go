@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// A scheduled chirp is pending until it's published or fails to. It's
+// checked again when it's due, so one that no longer passes, say because the
+// author lost Chirpy Red, fails with the reason in its error.
+const (
+	maxScheduleAhead    = 365 * 24 * time.Hour
+	scheduledChirpBatch = 100
+)
+
+// A scheduled chirp that errors while publishing, as opposed to failing its
+// checks, is tried again after scheduledChirpRetryDelay, and failed after
+// maxScheduledChirpAttempts.
+const (
+	scheduledChirpRetryDelay  = 5 * time.Minute
+	maxScheduledChirpAttempts = 5
+)
+
+type ScheduledChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Body      string     `json:"body"`
+	PublishAt time.Time  `json:"publish_at"`
+	Status    string     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+}
+
+func scheduledChirpFromDB(chirp database.ScheduledChirp) ScheduledChirp {
+	s := ScheduledChirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		Body:      chirp.Body,
+		PublishAt: chirp.PublishAt,
+		Status:    chirp.Status,
+		Error:     chirp.Error.String,
+	}
+	if chirp.ChirpID.Valid {
+		s.ChirpID = &chirp.ChirpID.UUID
+	}
+	return s
+}
+
+// CreateScheduledChirpHandler saves a chirp to be published later. It sits
+// behind MiddlewareRequireFeature, which hands over the user.
+func (cfg *ApiConfig) CreateScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type ScheduleRequest struct {
+		Body      string    `json:"body"`
+		PublishAt time.Time `json:"publish_at"`
+	}
+
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		respondWithError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Failed to parse chirp", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if !req.PublishAt.After(now) || req.PublishAt.After(now.Add(maxScheduleAhead)) {
+		respondWithError(w, "publish_at must be in the next 365 days", http.StatusBadRequest)
+		return
+	}
+
+	if cfg.RequireEmailVerification && !user.EmailVerified {
+		respondWithError(w, "Verify your email address before posting", http.StatusForbidden)
+		return
+	}
+
+	// the body is cleaned when it's published, against the banned words of
+	// the day
+	if _, verr := cfg.validateChirp(req.Body, user); verr != nil {
+		respondWithValidationError(w, verr)
+		return
+	}
+
+	if wait, ok := cfg.allowChirpWrite(user); !ok {
+		respondWithRateLimit(w, wait)
+		return
+	}
+
+	scheduled, err := cfg.DbQueries.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+		UserID:    user.ID,
+		Body:      req.Body,
+		PublishAt: req.PublishAt.UTC(),
+	})
+	if err != nil {
+		respondWithError(w, "Failed to schedule chirp", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, scheduledChirpFromDB(scheduled), http.StatusCreated)
+}
+
+// ListScheduledChirpsHandler isn't gated, so users who lost Chirpy Red can
+// still see and cancel what they scheduled.
+func (cfg *ApiConfig) ListScheduledChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateScopedToken(r.Context(), r.Header, ScopeChirpsRead)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.DbQueries.ListScheduledChirps(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting scheduled chirps", http.StatusInternalServerError)
+		return
+	}
+
+	chirps := []ScheduledChirp{}
+	for _, row := range rows {
+		chirps = append(chirps, scheduledChirpFromDB(row))
+	}
+
+	respondWithJSON(w, chirps, http.StatusOK)
+}
+
+func (cfg *ApiConfig) DeleteScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateScopedToken(r.Context(), r.Header, ScopeChirpsWrite)
+	if err != nil {
+		respondWithAuthError(w, err)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, "Invalid scheduled chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := cfg.DbQueries.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:     id,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, "Failed to cancel scheduled chirp", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, "Pending scheduled chirp not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PublishScheduledChirps publishes every scheduled chirp that's due and
+// returns how many were published. One that errors is put off until its
+// retry, so it doesn't stop the others going out.
+func (cfg *ApiConfig) PublishScheduledChirps(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		due, err := cfg.DbQueries.ListDueScheduledChirps(ctx, scheduledChirpBatch)
+		if err != nil {
+			return published, fmt.Errorf("error getting due chirps: %w", err)
+		}
+
+		for _, scheduled := range due {
+			ok, err := cfg.publishScheduledChirp(ctx, scheduled)
+			if err != nil {
+				log.Printf("Error publishing scheduled chirp %v (attempt %d): %v", scheduled.ID, scheduled.Attempts+1, err)
+
+				// without this the chirp would come straight back in the
+				// next batch
+				err = cfg.DbQueries.RetryScheduledChirp(ctx, database.RetryScheduledChirpParams{
+					Error:             sql.NullString{String: "error publishing chirp", Valid: true},
+					RetryAfterSeconds: scheduledChirpRetryDelay.Seconds(),
+					MaxAttempts:       maxScheduledChirpAttempts,
+					ID:                scheduled.ID,
+				})
+				if err != nil {
+					return published, fmt.Errorf("error recording failed attempt: %w", err)
+				}
+				continue
+			}
+			if ok {
+				published++
+			}
+		}
+
+		if len(due) < scheduledChirpBatch {
+			return published, nil
+		}
+	}
+}
+
+// publishScheduledChirp reports whether the chirp went out. One that fails
+// the checks is marked failed and doesn't count as an error.
+func (cfg *ApiConfig) publishScheduledChirp(ctx context.Context, scheduled database.ScheduledChirp) (bool, error) {
+	author, err := cfg.DbQueries.GetUserByID(ctx, scheduled.UserID)
+	if err != nil {
+		return false, fmt.Errorf("error getting author: %w", err)
+	}
+
+	reason := ""
+	body, verr := cfg.validateChirp(scheduled.Body, author)
+	if eerr := checkFeature(author, FeatureScheduledChirps); eerr != nil {
+		reason = eerr.Error
+	} else if verr != nil {
+		reason = verr.Error
+	}
+
+	if reason != "" {
+		err := cfg.DbQueries.FailScheduledChirp(ctx, database.FailScheduledChirpParams{
+			ID:    scheduled.ID,
+			Error: sql.NullString{String: reason, Valid: true},
+		})
+		if err != nil {
+			return false, fmt.Errorf("error failing scheduled chirp: %w", err)
+		}
+		return false, nil
+	}
+
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	chirp, err := insertChirp(ctx, qtx, database.CreateChirpParams{
+		Body:   body,
+		UserID: author.ID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// another instance may have published it while we weren't looking
+	updated, err := qtx.PublishScheduledChirp(ctx, database.PublishScheduledChirpParams{
+		ID:      scheduled.ID,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+	if err != nil {
+		return false, fmt.Errorf("error marking scheduled chirp published: %w", err)
+	}
+	if updated == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing chirp: %w", err)
+	}
+
+	return true, nil
+}
+
+// RunScheduledChirpPublisher calls PublishScheduledChirps every interval
+// until ctx is done.
+func (cfg *ApiConfig) RunScheduledChirpPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := cfg.PublishScheduledChirps(ctx); err != nil {
+			log.Printf("Error publishing scheduled chirps: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/moderation"
+)
+
+// fakeScheduledChirp is a scheduled_chirps row kept by the scheduled tests.
+type fakeScheduledChirp struct {
+	id       uuid.UUID
+	userID   uuid.UUID
+	body     string
+	status   string
+	error    any
+	chirpID  any
+	attempts int
+	retryAt  any
+}
+
+func (s *fakeScheduledChirp) row() []driver.Value {
+	now := time.Now()
+	return []driver.Value{
+		s.id.String(), now, now, s.userID.String(), s.body, now.Add(-time.Minute), s.status, s.error,
+		s.chirpID, int64(s.attempts), s.retryAt,
+	}
+}
+
+// TestPublishScheduledChirps publishes a batch in which one chirp can't be
+// published right now and another no longer passes its checks. Neither
+// holds up the chirp after them, and the one that errored is retried until
+// it runs out of attempts.
+func TestPublishScheduledChirps(t *testing.T) {
+	broken := uuid.New()
+	red := database.User{ID: uuid.New(), IsChirpyRed: true}
+	lapsed := database.User{ID: uuid.New()}
+
+	erroring := &fakeScheduledChirp{id: uuid.New(), userID: broken, body: "Say my name", status: "pending"}
+	good := &fakeScheduledChirp{id: uuid.New(), userID: red.ID, body: "I am the one who knocks", status: "pending"}
+	unentitled := &fakeScheduledChirp{id: uuid.New(), userID: lapsed.ID, body: "Yeah, science!", status: "pending"}
+	scheduled := []*fakeScheduledChirp{erroring, good, unentitled}
+
+	find := func(id driver.Value) *fakeScheduledChirp {
+		for _, s := range scheduled {
+			if s.id.String() == id {
+				return s
+			}
+		}
+		t.Fatalf("no scheduled chirp %v", id)
+		return nil
+	}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"ListDueScheduledChirps": func(args []driver.Value) ([][]driver.Value, error) {
+			var rows [][]driver.Value
+			for _, s := range scheduled {
+				retryAt, waiting := s.retryAt.(time.Time)
+				if s.status == "pending" && (!waiting || !retryAt.After(time.Now())) {
+					rows = append(rows, s.row())
+				}
+			}
+			return rows, nil
+		},
+		"GetUserByID": func(args []driver.Value) ([][]driver.Value, error) {
+			for _, user := range []database.User{red, lapsed} {
+				if user.ID.String() == args[0] {
+					return [][]driver.Value{fakeUserRow(user)}, nil
+				}
+			}
+			return nil, errors.New("connection reset")
+		},
+		"FailScheduledChirp": func(args []driver.Value) ([][]driver.Value, error) {
+			s := find(args[0])
+			s.status, s.error = "failed", args[1]
+			return nil, nil
+		},
+		"RetryScheduledChirp": func(args []driver.Value) ([][]driver.Value, error) {
+			s := find(args[3])
+			s.attempts++
+			s.error = args[0]
+			s.retryAt = time.Now().Add(time.Duration(args[1].(float64)) * time.Second)
+			if s.attempts >= int(args[2].(int64)) {
+				s.status = "failed"
+			}
+			return nil, nil
+		},
+		"CreateChirp": func(args []driver.Value) ([][]driver.Value, error) {
+			now := time.Now()
+			return [][]driver.Value{{uuid.NewString(), now, now, args[0], args[1], nil, nil, nil}}, nil
+		},
+		"EnqueueWebhookDeliveries": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"PublishScheduledChirp": func(args []driver.Value) ([][]driver.Value, error) {
+			s := find(args[0])
+			s.status, s.chirpID = "published", args[1]
+			return [][]driver.Value{{}}, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Profanity: moderation.NewFilter(nil)}
+
+	published, err := cfg.PublishScheduledChirps(context.Background())
+	if err != nil {
+		t.Fatalf("PublishScheduledChirps: %v", err)
+	}
+	if published != 1 {
+		t.Errorf("published %d, want 1", published)
+	}
+	if good.status != "published" || good.chirpID == nil {
+		t.Errorf("good chirp is %s with chirp %v, want published", good.status, good.chirpID)
+	}
+	if unentitled.status != "failed" || unentitled.error == nil {
+		t.Errorf("unentitled chirp is %s with error %v, want failed", unentitled.status, unentitled.error)
+	}
+	if erroring.status != "pending" || erroring.attempts != 1 || erroring.retryAt == nil {
+		t.Errorf("erroring chirp is %s after %d attempts, want pending after 1 with a retry", erroring.status, erroring.attempts)
+	}
+
+	// it isn't due again until its retry
+	if published, err := cfg.PublishScheduledChirps(context.Background()); err != nil || published != 0 {
+		t.Errorf("publishing again = %d, %v", published, err)
+	}
+	if erroring.attempts != 1 {
+		t.Errorf("erroring chirp was retried before its time, %d attempts", erroring.attempts)
+	}
+
+	for erroring.status == "pending" {
+		if erroring.attempts > maxScheduledChirpAttempts {
+			t.Fatalf("erroring chirp still pending after %d attempts", erroring.attempts)
+		}
+		erroring.retryAt = time.Now()
+		if _, err := cfg.PublishScheduledChirps(context.Background()); err != nil {
+			t.Fatalf("PublishScheduledChirps: %v", err)
+		}
+	}
+	if erroring.status != "failed" || erroring.attempts != maxScheduledChirpAttempts {
+		t.Errorf("erroring chirp is %s after %d attempts, want failed after %d",
+			erroring.status, erroring.attempts, maxScheduledChirpAttempts)
+	}
+}
+
+func TestDeleteScheduledChirp(t *testing.T) {
+	userID := uuid.New()
+	pending := &fakeScheduledChirp{id: uuid.New(), userID: userID, status: "pending"}
+	published := &fakeScheduledChirp{id: uuid.New(), userID: userID, status: "published"}
+	someoneElses := &fakeScheduledChirp{id: uuid.New(), userID: uuid.New(), status: "pending"}
+	scheduled := []*fakeScheduledChirp{pending, published, someoneElses}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"SessionIsActive": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{true}}, nil
+		},
+		"DeleteScheduledChirp": func(args []driver.Value) ([][]driver.Value, error) {
+			for i, s := range scheduled {
+				if s.id.String() == args[0] && s.userID.String() == args[1] && s.status == "pending" {
+					scheduled = append(scheduled[:i], scheduled[i+1:]...)
+					return [][]driver.Value{{}}, nil
+				}
+			}
+			return nil, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Keys: auth.NewHMACKeySet("test-secret")}
+	token, err := cfg.Keys.MakeSessionJWT(userID, uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeSessionJWT: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"pending", pending.id.String(), http.StatusNoContent},
+		{"already canceled", pending.id.String(), http.StatusNotFound},
+		{"published", published.id.String(), http.StatusNotFound},
+		{"someone else's", someoneElses.id.String(), http.StatusNotFound},
+		{"bad id", "heisenberg", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/api/scheduled-chirps/"+tt.id, nil)
+		req.SetPathValue("id", tt.id)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		cfg.DeleteScheduledChirpHandler(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+	if len(scheduled) != 2 {
+		t.Errorf("%d scheduled chirps left, want 2", len(scheduled))
+	}
+}
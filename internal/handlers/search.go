@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"database/sql"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+type SearchResult struct {
+	Chirp
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// ts_headline marks matches with these, so the snippet can be escaped before
+// they're turned into <mark> tags. The query strips them from bodies first.
+const (
+	snippetStartSel = "\x02"
+	snippetStopSel  = "\x03"
+)
+
+var snippetMarks = strings.NewReplacer(snippetStartSel, "<mark>", snippetStopSel, "</mark>")
+
+// highlightSnippet escapes a snippet for HTML and wraps its matches in
+// <mark> tags.
+func highlightSnippet(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
+
+func searchCursor(row database.SearchChirpsRow) string {
+	rank := row.Rank
+	return encodeCursor(pageCursor{CreatedAt: row.CreatedAt, ID: row.ID, Rank: &rank})
+}
+
+// SearchChirpsHandler runs a full-text search over chirp bodies. Results are
+// ranked best match first, or worst first with sort=asc, and snippets wrap
+// matches in <mark> tags.
+func (cfg *ApiConfig) SearchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, "Missing search query", http.StatusBadRequest)
+		return
+	}
+	sort_asc := r.URL.Query().Get("sort") == "asc"
+
+	authorID := uuid.NullUUID{}
+	if author_id := r.URL.Query().Get("author_id"); author_id != "" {
+		parsedID, err := uuid.Parse(author_id)
+		if err != nil {
+			respondWithError(w, "Invalid author id", http.StatusBadRequest)
+			return
+		}
+		authorID = uuid.NullUUID{UUID: parsedID, Valid: true}
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// paging backwards walks the opposite direction from the cursor
+	backwards := page.Before != nil
+	cursor := page.After
+	if backwards {
+		cursor = page.Before
+	}
+
+	cursorRank := sql.NullFloat64{}
+	if cursor != nil {
+		if cursor.Rank == nil {
+			respondWithError(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		cursorRank = sql.NullFloat64{Float64: float64(*cursor.Rank), Valid: true}
+	}
+	cursorCreatedAt, cursorID := cursorArgs(cursor)
+
+	params := database.SearchChirpsParams{
+		Query:           query,
+		AuthorID:        authorID,
+		CursorRank:      cursorRank,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        page.Limit + 1,
+	}
+
+	var rows []database.SearchChirpsRow
+	if sort_asc != backwards {
+		var ascRows []database.SearchChirpsAscRow
+		ascRows, err = cfg.DbQueries.SearchChirpsAsc(r.Context(), database.SearchChirpsAscParams(params))
+		for _, row := range ascRows {
+			rows = append(rows, database.SearchChirpsRow(row))
+		}
+	} else {
+		rows, err = cfg.DbQueries.SearchChirps(r.Context(), params)
+	}
+	if err != nil {
+		respondWithError(w, "Error searching chirps", http.StatusInternalServerError)
+		return
+	}
+
+	rows, hasNext, hasPrev := pageRows(rows, page.Limit, backwards, cursor != nil)
+
+	chirps := make([]Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = chirpFromDB(database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			DeletedAt: row.DeletedAt,
+			EditedAt:  row.EditedAt,
+		})
+	}
+
+	if err := cfg.decorateChirps(r.Context(), chirps, cfg.viewerID(r.Context(), r.Header)); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
+	}
+
+	resp := SearchPage{Results: []SearchResult{}}
+	for i, row := range rows {
+		resp.Results = append(resp.Results, SearchResult{
+			Chirp:   chirps[i],
+			Rank:    row.Rank,
+			Snippet: highlightSnippet(row.Snippet),
+		})
+	}
+
+	if hasNext {
+		resp.NextCursor = searchCursor(rows[len(rows)-1])
+	}
+	if hasPrev {
+		resp.PrevCursor = searchCursor(rows[0])
+	}
+
+	respondWithJSON(w, resp, http.StatusOK)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"a \x02kerfuffle\x03 today", "a <mark>kerfuffle</mark> today"},
+		{"<img src=x onerror=alert(1)> \x02term\x03", "&lt;img src=x onerror=alert(1)&gt; <mark>term</mark>"},
+		{`"quoted" & 'single'`, "&#34;quoted&#34; &amp; &#39;single&#39;"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+// A cursor from GET /api/chirps has no rank and can't place a search result.
+func TestSearchRejectsCursorWithoutRank(t *testing.T) {
+	cfg := &ApiConfig{}
+	cursor := encodeCursor(pageCursor{CreatedAt: time.Now(), ID: uuid.New()})
+
+	for _, param := range []string{"after", "before"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps/search?q=bird&"+param+"="+cursor, nil)
+		rec := httptest.NewRecorder()
+
+		cfg.SearchChirpsHandler(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", param, rec.Code)
+		}
+	}
+}
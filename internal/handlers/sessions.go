@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// Session is one logged in device. Its ID is the refresh token family, which
+// stays the same while the refresh token itself rotates.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// startSession starts a new token family for user and returns them with a
+// fresh access and refresh token, ready to send back from a login.
+func (cfg *ApiConfig) startSession(r *http.Request, user database.User) (User, error) {
+	refreshTokenID, err := auth.MakeRefreshToken()
+	if err != nil {
+		return User{}, err
+	}
+
+	refreshToken, err := cfg.DbQueries.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:     refreshTokenID,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(time.Hour * (24 * 60)),
+		FamilyID:  uuid.New(),
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		return User{}, fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	token, err := cfg.Keys.MakeSessionJWT(user.ID, refreshToken.FamilyID, time.Hour)
+	if err != nil {
+		return User{}, fmt.Errorf("error creating JWT: %w", err)
+	}
+
+	resp := userFromDB(user)
+	resp.Token = token
+	resp.RefreshToken = refreshToken.Token
+	return resp, nil
+}
+
+func (cfg *ApiConfig) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID, err := cfg.ValidateAccessTokenSession(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := cfg.DbQueries.GetActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting sessions", http.StatusInternalServerError)
+		return
+	}
+
+	sessions := []Session{}
+	for _, row := range rows {
+		sessions = append(sessions, Session{
+			ID:         row.FamilyID,
+			UserAgent:  row.UserAgent,
+			IPAddress:  row.IpAddress,
+			CreatedAt:  row.SessionCreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			Current:    row.FamilyID == sessionID,
+		})
+	}
+
+	respondWithJSON(w, sessions, http.StatusOK)
+}
+
+func (cfg *ApiConfig) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := cfg.DbQueries.GetActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting sessions", http.StatusInternalServerError)
+		return
+	}
+
+	for _, row := range rows {
+		if row.FamilyID != sessionID {
+			continue
+		}
+
+		if err := cfg.DbQueries.RevokeRefreshToken(r.Context(), row.Token); err != nil {
+			respondWithError(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	respondWithError(w, "Session not found", http.StatusNotFound)
+}
+
+// RevokeAllSessionsHandler logs the user out everywhere, including the
+// session making the request.
+func (cfg *ApiConfig) RevokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := cfg.DbQueries.GetActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting sessions", http.StatusInternalServerError)
+		return
+	}
+
+	for _, row := range rows {
+		if err := cfg.DbQueries.RevokeRefreshToken(r.Context(), row.Token); err != nil {
+			respondWithError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+)
+
+// Logging out only revokes tokens tied to a session, so a token without one
+// must not be accepted at all. None of these get as far as the database.
+func TestValidateAccessTokenRejectsTokensWithoutSession(t *testing.T) {
+	cfg := &ApiConfig{Keys: auth.NewHMACKeySet("test-secret")}
+
+	token, err := cfg.Keys.MakeJWT(uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT: %v", err)
+	}
+
+	for _, value := range []string{"", "Bearer ", "Bearer nonsense", "Bearer " + token} {
+		headers := http.Header{}
+		headers.Set("Authorization", value)
+
+		if _, err := cfg.ValidateAccessToken(context.Background(), headers); err == nil {
+			t.Errorf("token %q was accepted", value)
+		}
+	}
+}
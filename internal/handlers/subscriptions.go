@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// A Chirpy Red subscription is active while paid for. When a period ends
+// without a renewal it goes past_due and keeps its perks for the grace
+// period, then expires. A canceled subscription runs to the end of its
+// period and then expires with no grace.
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionExpired  = "expired"
+)
+
+const (
+	planRed            = "red"
+	subscriptionPeriod = 30 * 24 * time.Hour
+
+	// DefaultRedGracePeriod is how long a lapsed subscription keeps
+	// Chirpy Red while Polka retries the payment.
+	DefaultRedGracePeriod = 72 * time.Hour
+)
+
+// Polka events about a user's subscription.
+const (
+	polkaEventUpgraded   = "user.upgraded"
+	polkaEventRenewed    = "user.renewed"
+	polkaEventCanceled   = "user.canceled"
+	polkaEventDowngraded = "user.downgraded"
+)
+
+var errWebhookSubscriptionNotFound = errors.New("subscription not found")
+
+func isSubscriptionEvent(event string) bool {
+	switch event {
+	case polkaEventUpgraded, polkaEventRenewed, polkaEventCanceled, polkaEventDowngraded:
+		return true
+	}
+	return false
+}
+
+// upgradePeriodEnd is when a subscription started now without a period end
+// from Polka runs until: a period from now, or the end of the current period
+// if that's later. It never adds to the current period, so an upgrade that
+// is applied twice doesn't pay for two.
+func upgradePeriodEnd(current, now time.Time) time.Time {
+	if next := now.Add(subscriptionPeriod); next.After(current) {
+		return next
+	}
+	return current
+}
+
+// applySubscriptionEvent updates the user's subscription and their Chirpy
+// Red flag together.
+func (cfg *ApiConfig) applySubscriptionEvent(ctx context.Context, event polkaEvent, userID uuid.UUID) error {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	switch event.Event {
+	case polkaEventUpgraded, polkaEventRenewed:
+		if err := setChirpyRed(ctx, qtx, userID, true); err != nil {
+			return err
+		}
+
+		// a renewal says which period it paid for. Extending by a period
+		// instead would pay twice for a renewal that's replayed.
+		periodEnd := time.Time{}
+		if event.Data.CurrentPeriodEnd != nil {
+			periodEnd = *event.Data.CurrentPeriodEnd
+		} else if event.Event == polkaEventRenewed {
+			return fmt.Errorf("%w: renewal without current_period_end", errWebhookBadPayload)
+		} else {
+			current, err := qtx.GetSubscription(ctx, userID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("error getting subscription: %w", err)
+			}
+			periodEnd = upgradePeriodEnd(current.CurrentPeriodEnd, time.Now())
+		}
+
+		plan := event.Data.Plan
+		if plan == "" {
+			plan = planRed
+		}
+
+		_, err = qtx.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+			UserID:           userID,
+			Plan:             plan,
+			Status:           subscriptionActive,
+			CurrentPeriodEnd: periodEnd,
+		})
+		if err != nil {
+			return fmt.Errorf("error saving subscription: %w", err)
+		}
+
+		// renewals aren't news to anyone listening
+		if event.Event == polkaEventUpgraded {
+			err = enqueueWebhookEvent(ctx, qtx, WebhookEventUserUpgraded, userID, userUpgradedEventData{
+				UserID:           userID,
+				Plan:             plan,
+				CurrentPeriodEnd: periodEnd,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+	case polkaEventCanceled:
+		// the user keeps what they paid for, the sweep expires it
+		_, err = qtx.SetSubscriptionStatus(ctx, database.SetSubscriptionStatusParams{
+			UserID: userID,
+			Status: subscriptionCanceled,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return errWebhookSubscriptionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error canceling subscription: %w", err)
+		}
+
+	case polkaEventDowngraded:
+		if err := setChirpyRed(ctx, qtx, userID, false); err != nil {
+			return err
+		}
+
+		// members from before subscriptions were tracked may not have one
+		_, err = qtx.SetSubscriptionStatus(ctx, database.SetSubscriptionStatusParams{
+			UserID: userID,
+			Status: subscriptionExpired,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("error expiring subscription: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func setChirpyRed(ctx context.Context, q *database.Queries, userID uuid.UUID, red bool) error {
+	updated, err := q.SetChirpyRed(ctx, database.SetChirpyRedParams{
+		ID:          userID,
+		IsChirpyRed: red,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating user: %w", err)
+	}
+	if updated == 0 {
+		return errWebhookUserNotFound
+	}
+	return nil
+}
+
+// SweepSubscriptions moves lapsed subscriptions along and takes Chirpy Red
+// away from the ones that expired. It returns how many expired.
+func (cfg *ApiConfig) SweepSubscriptions(ctx context.Context) (int, error) {
+	if _, err := cfg.DbQueries.MarkSubscriptionsPastDue(ctx); err != nil {
+		return 0, fmt.Errorf("error marking subscriptions past due: %w", err)
+	}
+
+	expired, err := cfg.DbQueries.ExpireSubscriptions(ctx, cfg.RedGracePeriod.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("error expiring subscriptions: %w", err)
+	}
+
+	return len(expired), nil
+}
+
+// RunSubscriptionSweeper calls SweepSubscriptions every interval until ctx
+// is done.
+func (cfg *ApiConfig) RunSubscriptionSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := cfg.SweepSubscriptions(ctx)
+		if err != nil {
+			log.Printf("Error sweeping subscriptions: %v", err)
+		} else if expired > 0 {
+			log.Printf("Expired %d Chirpy Red subscriptions", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+)
+
+func TestUpgradePeriodEnd(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		current time.Time
+		want    time.Time
+	}{
+		{"no subscription", time.Time{}, now.Add(subscriptionPeriod)},
+		{"lapsed", now.Add(-48 * time.Hour), now.Add(subscriptionPeriod)},
+		{"ends sooner", now.Add(5 * 24 * time.Hour), now.Add(subscriptionPeriod)},
+		{"ends later", now.Add(40 * 24 * time.Hour), now.Add(40 * 24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		if got := upgradePeriodEnd(tt.current, now); !got.Equal(tt.want) {
+			t.Errorf("%s: upgradePeriodEnd() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// fakeSubscription is a subscriptions row kept by the subscription tests.
+type fakeSubscription struct {
+	plan      string
+	status    string
+	periodEnd time.Time
+}
+
+func (s *fakeSubscription) row(userID uuid.UUID) []driver.Value {
+	now := time.Now()
+	return []driver.Value{userID.String(), now, now, s.plan, s.status, s.periodEnd}
+}
+
+// TestSubscriptionWebhooks sends Polka events through the webhook handler
+// and follows one subscription from upgrade to downgrade.
+func TestSubscriptionWebhooks(t *testing.T) {
+	secret := "polka-secret"
+	userID := uuid.New()
+
+	isRed := false
+	var sub *fakeSubscription
+	upgradesAnnounced := 0
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"ClaimWebhookEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			now := time.Now()
+			return [][]driver.Value{{
+				uuid.NewString(), now, now, args[0], args[1], args[2], webhookStatusProcessing, nil, int64(1), nil,
+			}}, nil
+		},
+		"FinishWebhookEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			now := time.Now()
+			return [][]driver.Value{{
+				args[0], now, now, "evt", "user.upgraded", "{}", args[1], args[2], int64(1), now,
+			}}, nil
+		},
+		"SetChirpyRed": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[0] != userID.String() {
+				return nil, nil
+			}
+			isRed = args[1].(bool)
+			return [][]driver.Value{{}}, nil
+		},
+		"GetSubscription": func(args []driver.Value) ([][]driver.Value, error) {
+			if sub == nil {
+				return nil, nil
+			}
+			return [][]driver.Value{sub.row(userID)}, nil
+		},
+		"UpsertSubscription": func(args []driver.Value) ([][]driver.Value, error) {
+			sub = &fakeSubscription{plan: args[1].(string), status: args[2].(string), periodEnd: args[3].(time.Time)}
+			return [][]driver.Value{sub.row(userID)}, nil
+		},
+		"SetSubscriptionStatus": func(args []driver.Value) ([][]driver.Value, error) {
+			if sub == nil {
+				return nil, nil
+			}
+			sub.status = args[1].(string)
+			return [][]driver.Value{sub.row(userID)}, nil
+		},
+		"EnqueueWebhookDeliveries": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[1] == WebhookEventUserUpgraded {
+				upgradesAnnounced++
+			}
+			return nil, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, PolkaSecrets: []string{secret}}
+
+	deliver := func(event, periodEnd string) int {
+		t.Helper()
+		data := fmt.Sprintf(`"user_id":%q`, userID)
+		if periodEnd != "" {
+			data += fmt.Sprintf(`,"current_period_end":%q`, periodEnd)
+		}
+		body := []byte(fmt.Sprintf(`{"id":%q,"event":%q,"data":{%s}}`, uuid.NewString(), event, data))
+
+		now := time.Now()
+		req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", bytes.NewReader(body))
+		req.Header.Set(auth.WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(auth.WebhookSignatureHeader, auth.SignWebhook(secret, now, body))
+		rec := httptest.NewRecorder()
+		cfg.WebhookUpgradeUserHandler(rec, req)
+		return rec.Code
+	}
+	expect := func(step string, wantRed bool, wantStatus string, wantEnd time.Time) {
+		t.Helper()
+		if isRed != wantRed {
+			t.Errorf("%s: Chirpy Red = %v, want %v", step, isRed, wantRed)
+		}
+		if sub == nil {
+			t.Fatalf("%s: no subscription", step)
+		}
+		if sub.status != wantStatus {
+			t.Errorf("%s: status = %s, want %s", step, sub.status, wantStatus)
+		}
+		if !sub.periodEnd.Equal(wantEnd) {
+			t.Errorf("%s: period ends %v, want %v", step, sub.periodEnd, wantEnd)
+		}
+	}
+
+	// canceling a subscription nobody has
+	if code := deliver(polkaEventCanceled, ""); code != http.StatusNotFound {
+		t.Errorf("cancel without a subscription returned %d, want 404", code)
+	}
+
+	may := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	june := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if code := deliver(polkaEventUpgraded, may.Format(time.RFC3339)); code != http.StatusNoContent {
+		t.Fatalf("upgrade returned %d", code)
+	}
+	expect("upgrade", true, subscriptionActive, may)
+	if sub.plan != planRed {
+		t.Errorf("upgrade: plan = %s, want %s", sub.plan, planRed)
+	}
+	if upgradesAnnounced != 1 {
+		t.Errorf("upgrade: announced %d upgrades, want 1", upgradesAnnounced)
+	}
+
+	// a renewal has to say which period it paid for
+	if code := deliver(polkaEventRenewed, ""); code != http.StatusBadRequest {
+		t.Errorf("renewal without a period end returned %d, want 400", code)
+	}
+	expect("renewal without a period end", true, subscriptionActive, may)
+
+	// and saying it twice doesn't pay for two
+	for range 2 {
+		if code := deliver(polkaEventRenewed, june.Format(time.RFC3339)); code != http.StatusNoContent {
+			t.Fatalf("renewal returned %d", code)
+		}
+	}
+	expect("renewal", true, subscriptionActive, june)
+	if upgradesAnnounced != 1 {
+		t.Errorf("renewal: announced %d upgrades, want 1", upgradesAnnounced)
+	}
+
+	if code := deliver(polkaEventCanceled, ""); code != http.StatusNoContent {
+		t.Fatalf("cancel returned %d", code)
+	}
+	expect("cancel", true, subscriptionCanceled, june)
+
+	if code := deliver(polkaEventDowngraded, ""); code != http.StatusNoContent {
+		t.Fatalf("downgrade returned %d", code)
+	}
+	expect("downgrade", false, subscriptionExpired, june)
+
+	// upgrading again without a period end starts one period from now, and
+	// doesn't stack another on top when it's applied again
+	before := time.Now()
+	for range 2 {
+		if code := deliver(polkaEventUpgraded, ""); code != http.StatusNoContent {
+			t.Fatalf("upgrade returned %d", code)
+		}
+	}
+	if !isRed || sub.status != subscriptionActive {
+		t.Errorf("second upgrade: Chirpy Red = %v, status = %s", isRed, sub.status)
+	}
+	if sub.periodEnd.Before(before.Add(subscriptionPeriod)) || sub.periodEnd.After(time.Now().Add(subscriptionPeriod)) {
+		t.Errorf("second upgrade: period ends %v, want a period from now", sub.periodEnd)
+	}
+}
+
+// TestSweepSubscriptions runs the sweep over a subscription in each state.
+// The fakes do what the queries' WHERE clauses do.
+func TestSweepSubscriptions(t *testing.T) {
+	now := time.Now()
+	grace := 72 * time.Hour
+
+	subs := map[string]*fakeSubscription{
+		"active":              {status: subscriptionActive, periodEnd: now.Add(time.Hour)},
+		"lapsed":              {status: subscriptionActive, periodEnd: now.Add(-time.Hour)},
+		"past due in grace":   {status: subscriptionPastDue, periodEnd: now.Add(-grace / 2)},
+		"past due, no grace":  {status: subscriptionPastDue, periodEnd: now.Add(-2 * grace)},
+		"canceled, paid up":   {status: subscriptionCanceled, periodEnd: now.Add(time.Hour)},
+		"canceled, period up": {status: subscriptionCanceled, periodEnd: now.Add(-time.Hour)},
+	}
+	want := map[string]string{
+		"active":              subscriptionActive,
+		"lapsed":              subscriptionPastDue,
+		"past due in grace":   subscriptionPastDue,
+		"past due, no grace":  subscriptionExpired,
+		"canceled, paid up":   subscriptionCanceled,
+		"canceled, period up": subscriptionExpired,
+	}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"MarkSubscriptionsPastDue": func(args []driver.Value) ([][]driver.Value, error) {
+			var rows [][]driver.Value
+			for _, sub := range subs {
+				if sub.status == subscriptionActive && sub.periodEnd.Before(time.Now()) {
+					sub.status = subscriptionPastDue
+					rows = append(rows, nil)
+				}
+			}
+			return rows, nil
+		},
+		"ExpireSubscriptions": func(args []driver.Value) ([][]driver.Value, error) {
+			graceSeconds := args[0].(float64)
+			var rows [][]driver.Value
+			for _, sub := range subs {
+				lapsed := sub.status == subscriptionPastDue &&
+					sub.periodEnd.Before(time.Now().Add(-time.Duration(graceSeconds)*time.Second))
+				ended := sub.status == subscriptionCanceled && sub.periodEnd.Before(time.Now())
+				if lapsed || ended {
+					sub.status = subscriptionExpired
+					rows = append(rows, []driver.Value{uuid.NewString()})
+				}
+			}
+			return rows, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, RedGracePeriod: grace}
+
+	expired, err := cfg.SweepSubscriptions(context.Background())
+	if err != nil {
+		t.Fatalf("SweepSubscriptions: %v", err)
+	}
+	if expired != 2 {
+		t.Errorf("SweepSubscriptions expired %d, want 2", expired)
+	}
+	for name, sub := range subs {
+		if sub.status != want[name] {
+			t.Errorf("%s: status = %s, want %s", name, sub.status, want[name])
+		}
+	}
+}
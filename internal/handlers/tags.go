@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const (
+	maxTagsPerChirp = 10
+	maxTagLength    = 50
+
+	defaultTrendingWindow = 24 * time.Hour
+	minTrendingWindow     = time.Minute
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 50
+)
+
+// a hashtag must start the body or follow something that can't be part of a word
+var hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+type TrendingTag struct {
+	Tag   string  `json:"tag"`
+	Uses  int64   `json:"uses"`
+	Score float64 `json:"score"`
+}
+
+// extractHashtags returns the distinct, lowercased hashtags in body in the
+// order they first appear. Tags made only of digits are ignored.
+func extractHashtags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for _, match := range hashtagRegex.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(match[1])
+		if len([]rune(tag)) > maxTagLength || seen[tag] || !strings.ContainsFunc(tag, unicode.IsLetter) {
+			continue
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+		if len(tags) == maxTagsPerChirp {
+			break
+		}
+	}
+
+	return tags
+}
+
+func (cfg *ApiConfig) GetTagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, "Invalid tag", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if page.Before != nil {
+		respondWithError(w, "before is not supported here", http.StatusBadRequest)
+		return
+	}
+	cursorCreatedAt, cursorID := cursorArgs(page.After)
+
+	chirps, err := cfg.DbQueries.GetChirpsByTag(r.Context(), database.GetChirpsByTagParams{
+		Tag:             tag,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		RowLimit:        page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, "Error getting chirps for tag", http.StatusInternalServerError)
+		return
+	}
+
+	chirp_list := []Chirp{}
+	for _, chirp := range chirps {
+		chirp_list = append(chirp_list, chirpFromDB(chirp))
+	}
+
+	if err := cfg.decorateChirps(r.Context(), chirp_list, cfg.viewerID(r.Context(), r.Header)); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
+	}
+
+	// before isn't supported, so don't offer a cursor for it
+	respondWithJSON(w, newChirpPage(chirp_list, page.Limit, false, false), http.StatusOK)
+}
+
+// TrendingTagsHandler ranks tags used within the window (e.g. ?window=6h).
+// Every use counts for less the older it is, halving each quarter window.
+func (cfg *ApiConfig) TrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < minTrendingWindow || parsed > maxTrendingWindow {
+			respondWithError(w, "window must be between 1m and 168h", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+
+	limit := defaultTrendingLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			respondWithError(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, maxTrendingLimit)
+	}
+
+	rows, err := cfg.DbQueries.GetTrendingTags(r.Context(), database.GetTrendingTagsParams{
+		HalfLifeSeconds: (window / 4).Seconds(),
+		WindowSeconds:   window.Seconds(),
+		RowLimit:        int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, "Error getting trending tags", http.StatusInternalServerError)
+		return
+	}
+
+	tags := []TrendingTag{}
+	for _, row := range rows {
+		tags = append(tags, TrendingTag{Tag: row.Tag, Uses: row.Uses, Score: row.Score})
+	}
+
+	respondWithJSON(w, tags, http.StatusOK)
+}
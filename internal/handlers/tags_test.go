@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	cases := map[string][]string{
+		"no tags here":                      {},
+		"#Go is fun #go":                    {"go"},
+		"learning #golang, (#sql) and #db!": {"golang", "sql", "db"},
+		"email me@x.com#notatag and &#39;":  {},
+		"issue #123 but #2fa counts":        {"2fa"},
+		"#café time":                        {"café"},
+	}
+
+	for body, want := range cases {
+		got := extractHashtags(body)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("extractHashtags(%q) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+// None of these get as far as the database. A tiny window would make the
+// half-life zero and the query divide by it.
+func TestTrendingTagsRejectsBadWindow(t *testing.T) {
+	cfg := &ApiConfig{}
+	for _, window := range []string{"1ns", "59s", "0", "-1h", "169h", "soon"} {
+		rec := httptest.NewRecorder()
+		cfg.TrendingTagsHandler(rec, httptest.NewRequest(http.MethodGet, "/api/tags/trending?window="+window, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("window=%s: status = %d, want 400", window, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const (
+	maxThreadDepth   = 50
+	maxThreadReplies = 1000
+)
+
+// ThreadNode is a chirp together with the replies made to it.
+type ThreadNode struct {
+	Chirp
+	Replies []*ThreadNode `json:"replies"`
+}
+
+type Thread struct {
+	Ancestors []Chirp     `json:"ancestors"`
+	Chirp     *ThreadNode `json:"chirp"`
+}
+
+func (cfg *ApiConfig) GetThreadHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpid"))
+	if err != nil {
+		respondWithError(w, "Invalid chirp ID", http.StatusBadRequest)
+		return
+	}
+
+	chirp, err := cfg.DbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Chirp not found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Error getting chirp", http.StatusInternalServerError)
+		return
+	}
+
+	ancestors, err := cfg.DbQueries.GetChirpAncestors(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, "Error getting chirp ancestors", http.StatusInternalServerError)
+		return
+	}
+
+	replies, err := cfg.DbQueries.GetChirpReplies(r.Context(), database.GetChirpRepliesParams{
+		ChirpID:  uuid.NullUUID{UUID: chirpID, Valid: true},
+		MaxDepth: maxThreadDepth,
+		RowLimit: maxThreadReplies,
+	})
+	if err != nil {
+		respondWithError(w, "Error getting chirp replies", http.StatusInternalServerError)
+		return
+	}
+
+	thread := Thread{Ancestors: []Chirp{}}
+	for _, a := range ancestors {
+		thread.Ancestors = append(thread.Ancestors, chirpFromDB(database.Chirp{
+			ID:        a.ID,
+			CreatedAt: a.CreatedAt,
+			UpdatedAt: a.UpdatedAt,
+			Body:      a.Body,
+			UserID:    a.UserID,
+			InReplyTo: a.InReplyTo,
+			DeletedAt: a.DeletedAt,
+			EditedAt:  a.EditedAt,
+		}))
+	}
+
+	reply_list := []Chirp{}
+	for _, reply := range replies {
+		reply_list = append(reply_list, chirpFromDB(database.Chirp{
+			ID:        reply.ID,
+			CreatedAt: reply.CreatedAt,
+			UpdatedAt: reply.UpdatedAt,
+			Body:      reply.Body,
+			UserID:    reply.UserID,
+			InReplyTo: reply.InReplyTo,
+			DeletedAt: reply.DeletedAt,
+			EditedAt:  reply.EditedAt,
+		}))
+	}
+
+	var nodes []*ThreadNode
+	thread.Chirp, nodes = buildReplyTree(chirpFromDB(chirp), reply_list, maxThreadDepth)
+
+	// decorate every chirp in the thread in one go
+	all := append([]Chirp{}, thread.Ancestors...)
+	for _, node := range nodes {
+		all = append(all, node.Chirp)
+	}
+
+	if err := cfg.decorateChirps(r.Context(), all, cfg.viewerID(r.Context(), r.Header)); err != nil {
+		respondWithError(w, "Error getting chirp details", http.StatusInternalServerError)
+		return
+	}
+
+	copy(thread.Ancestors, all)
+	for i, node := range nodes {
+		node.Chirp = all[len(thread.Ancestors)+i]
+	}
+
+	respondWithJSON(w, thread, http.StatusOK)
+}
+
+// buildReplyTree hangs replies under root, oldest sibling first. Replies more
+// than maxDepth below root are left out, and so are replies whose parent
+// isn't in replies, say because it fell outside maxThreadReplies. It returns
+// the root node and every node in the tree, root first.
+func buildReplyTree(root Chirp, replies []Chirp, maxDepth int) (*ThreadNode, []*ThreadNode) {
+	children := map[uuid.UUID][]Chirp{}
+	for _, reply := range replies {
+		if reply.InReplyTo != nil {
+			children[*reply.InReplyTo] = append(children[*reply.InReplyTo], reply)
+		}
+	}
+
+	rootNode := &ThreadNode{Chirp: root, Replies: []*ThreadNode{}}
+	nodes := []*ThreadNode{rootNode}
+
+	// walk down a level at a time so the depth is known
+	level := []*ThreadNode{rootNode}
+	for depth := 0; depth < maxDepth && len(level) > 0; depth++ {
+		next := []*ThreadNode{}
+		for _, parent := range level {
+			siblings := children[parent.ID]
+			slices.SortFunc(siblings, func(a, b Chirp) int {
+				if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+					return c
+				}
+				return bytes.Compare(a.ID[:], b.ID[:])
+			})
+
+			for _, reply := range siblings {
+				node := &ThreadNode{Chirp: reply, Replies: []*ThreadNode{}}
+				parent.Replies = append(parent.Replies, node)
+				nodes = append(nodes, node)
+				next = append(next, node)
+			}
+		}
+		level = next
+	}
+
+	return rootNode, nodes
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func replyTo(parent Chirp, at time.Time) Chirp {
+	return Chirp{ID: uuid.New(), CreatedAt: at, InReplyTo: &parent.ID}
+}
+
+func TestBuildReplyTree(t *testing.T) {
+	base := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	root := Chirp{ID: uuid.New(), CreatedAt: base}
+
+	late := replyTo(root, base.Add(2*time.Minute))
+	early := replyTo(root, base.Add(time.Minute))
+	nested := replyTo(early, base.Add(3*time.Minute))
+	// its parent isn't among the replies, as if it fell outside the limit
+	orphan := Chirp{ID: uuid.New(), CreatedAt: base, InReplyTo: &uuid.UUID{1}}
+
+	tree, nodes := buildReplyTree(root, []Chirp{nested, late, orphan, early}, maxThreadDepth)
+
+	if tree.ID != root.ID {
+		t.Fatalf("root = %v, want %v", tree.ID, root.ID)
+	}
+	if len(nodes) != 4 || nodes[0] != tree {
+		t.Fatalf("got %d nodes, want the root and 3 replies", len(nodes))
+	}
+	if len(tree.Replies) != 2 || tree.Replies[0].ID != early.ID || tree.Replies[1].ID != late.ID {
+		t.Fatalf("root replies aren't oldest first")
+	}
+	if len(tree.Replies[0].Replies) != 1 || tree.Replies[0].Replies[0].ID != nested.ID {
+		t.Errorf("nested reply isn't under its parent")
+	}
+	for _, node := range nodes {
+		if node.ID == orphan.ID {
+			t.Errorf("reply without its parent made it into the tree")
+		}
+	}
+}
+
+func TestBuildReplyTreeSiblingTies(t *testing.T) {
+	at := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	root := Chirp{ID: uuid.New(), CreatedAt: at}
+
+	a := Chirp{ID: uuid.UUID{2}, CreatedAt: at, InReplyTo: &root.ID}
+	b := Chirp{ID: uuid.UUID{1}, CreatedAt: at, InReplyTo: &root.ID}
+
+	tree, _ := buildReplyTree(root, []Chirp{a, b}, maxThreadDepth)
+	if tree.Replies[0].ID != b.ID || tree.Replies[1].ID != a.ID {
+		t.Errorf("replies posted at the same time aren't ordered by ID")
+	}
+}
+
+func TestBuildReplyTreeDepthCap(t *testing.T) {
+	base := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	root := Chirp{ID: uuid.New(), CreatedAt: base}
+
+	replies := []Chirp{}
+	parent := root
+	for i := 1; i <= 5; i++ {
+		reply := replyTo(parent, base.Add(time.Duration(i)*time.Minute))
+		replies = append(replies, reply)
+		parent = reply
+	}
+
+	tree, nodes := buildReplyTree(root, replies, 3)
+	if len(nodes) != 4 {
+		t.Fatalf("got %d nodes, want the root and 3 levels of replies", len(nodes))
+	}
+
+	depth := 0
+	for node := tree; len(node.Replies) > 0; node = node.Replies[0] {
+		depth++
+	}
+	if depth != 3 {
+		t.Errorf("tree is %d levels deep, want 3", depth)
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// Failed logins are counted per account and per IP. Past the threshold each
+// further failure locks the key for twice as long as the last one, up to
+// maxLockout. Counts start over after a quiet failureWindow.
+const (
+	accountFailureThreshold = 5
+	ipFailureThreshold      = 20
+	baseLockout             = time.Minute
+	maxLockout              = time.Hour
+	failureWindow           = 24 * time.Hour
+)
+
+const securityEventAccountLocked = "account_locked"
+
+// loginThrottle names the keys a login attempt is counted against.
+type loginThrottle struct {
+	account string
+	ip      string
+}
+
+func newLoginThrottle(email, ip string) loginThrottle {
+	return loginThrottle{
+		account: accountThrottleKey(email),
+		ip:      "ip:" + ip,
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// lockoutFor is how long a key is locked after its nth failure.
+func lockoutFor(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	exp := failures - threshold
+	if exp > 16 {
+		return maxLockout
+	}
+
+	lockout := baseLockout * time.Duration(math.Pow(2, float64(exp)))
+	if lockout > maxLockout {
+		return maxLockout
+	}
+	return lockout
+}
+
+// lockedFor returns how long until both keys are unlocked, or 0.
+func (cfg *ApiConfig) lockedFor(ctx context.Context, t loginThrottle) (time.Duration, error) {
+	locks, err := cfg.DbQueries.GetLoginLocks(ctx, []string{t.account, t.ip})
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	for _, lock := range locks {
+		if d := time.Until(lock.LockedUntil.Time); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// recordLoginFailure counts a failure against both keys and locks the ones
+// that went over their threshold. userID is uuid.Nil for unknown emails,
+// which are counted all the same so lockouts don't reveal who has an account.
+func (cfg *ApiConfig) recordLoginFailure(ctx context.Context, t loginThrottle, userID uuid.UUID) {
+	for _, key := range []struct {
+		name      string
+		threshold int
+	}{{t.account, accountFailureThreshold}, {t.ip, ipFailureThreshold}} {
+		failures, err := cfg.DbQueries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Key:           key.name,
+			WindowSeconds: failureWindow.Seconds(),
+		})
+		if err != nil {
+			log.Printf("Error recording login failure for %s: %v", key.name, err)
+			continue
+		}
+
+		lockout := lockoutFor(int(failures), key.threshold)
+		if lockout == 0 {
+			continue
+		}
+
+		err = cfg.DbQueries.LockLoginThrottle(ctx, database.LockLoginThrottleParams{
+			Key:         key.name,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
+		})
+		if err != nil {
+			log.Printf("Error locking %s: %v", key.name, err)
+			continue
+		}
+
+		if key.name == t.account && userID != uuid.Nil {
+			err = cfg.DbQueries.CreateSecurityEvent(ctx, database.CreateSecurityEventParams{
+				UserID:    userID,
+				EventType: securityEventAccountLocked,
+				Details:   fmt.Sprintf("%d failed login attempts; locked for %v", failures, lockout),
+			})
+			if err != nil {
+				log.Printf("Error recording security event: %v", err)
+			}
+		}
+	}
+}
+
+// clearLoginFailures forgets an account's failures after a good login. The
+// IP keeps its count, or an attacker could reset it by logging into their
+// own account between guesses.
+func (cfg *ApiConfig) clearLoginFailures(ctx context.Context, t loginThrottle) {
+	if err := cfg.DbQueries.ClearLoginThrottle(ctx, t.account); err != nil {
+		log.Printf("Error clearing login failures for %s: %v", t.account, err)
+	}
+}
+
+func respondWithLockout(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondWithError(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// checkDummyPassword spends as long as a real password check, so a login
+// for an unknown email takes as long as one with a wrong password.
+func checkDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = auth.HashPassword("chirpy-dummy-password")
+	})
+	_ = auth.CheckPasswordHash(dummyHash, password)
+}
+
+// UnlockUserHandler lets an admin clear a locked out account.
+func (cfg *ApiConfig) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userid"))
+	if err != nil {
+		respondWithError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	if err := cfg.DbQueries.ClearLoginThrottle(r.Context(), accountThrottleKey(user.Email)); err != nil {
+		respondWithError(w, "Error unlocking user", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutFor(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, time.Hour},
+		{1000, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := lockoutFor(tt.failures, accountFailureThreshold); got != tt.want {
+			t.Errorf("lockoutFor(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestAccountThrottleKeyIgnoresCase(t *testing.T) {
+	if accountThrottleKey(" Walt@BreakingBad.com") != accountThrottleKey("walt@breakingbad.com") {
+		t.Errorf("account throttle key should ignore case and surrounding space")
+	}
+}
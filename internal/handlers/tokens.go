@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// Scopes a personal access token can be granted. JWTs from a login aren't
+// scoped and can do everything.
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeChirpsRead:  true,
+	ScopeChirpsWrite: true,
+}
+
+const (
+	maxTokenNameLength = 100
+	maxTokenLifetime   = 365 * 24 * time.Hour
+)
+
+var errMissingScope = errors.New("forbidden: token is missing the required scope")
+
+// PersonalAccessToken is how a token is listed. The token itself is only in
+// the response that creates it.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	TokenHint  string     `json:"token_hint"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func tokenFromDB(token database.PersonalAccessToken) PersonalAccessToken {
+	pat := PersonalAccessToken{
+		ID:        token.ID,
+		CreatedAt: token.CreatedAt,
+		Name:      token.Name,
+		TokenHint: token.TokenHint,
+		Scopes:    token.Scopes,
+	}
+	if token.ExpiresAt.Valid {
+		pat.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		pat.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return pat
+}
+
+// ValidateScopedToken accepts either a JWT or a personal access token that
+// has been granted scope. Only the endpoints bots need call this, everything
+// else keeps using ValidateAccessToken and so refuses PATs.
+func (cfg *ApiConfig) ValidateScopedToken(ctx context.Context, headers http.Header, scope string) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(headers)
+	if err != nil || token == "" {
+		return uuid.Nil, fmt.Errorf("unauthorized: invalid or missing bearer token")
+	}
+
+	if !auth.IsPersonalAccessToken(token) {
+		return cfg.ValidateAccessToken(ctx, headers)
+	}
+
+	pat, err := cfg.DbQueries.GetPersonalAccessTokenByHash(ctx, auth.HashPersonalAccessToken(token))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unauthorized: invalid token")
+	}
+
+	granted := false
+	for _, s := range pat.Scopes {
+		if s == scope {
+			granted = true
+			break
+		}
+	}
+	if !granted {
+		return uuid.Nil, errMissingScope
+	}
+
+	if err := cfg.DbQueries.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+		log.Printf("Error updating last use of token %v: %v", pat.ID, err)
+	}
+
+	return pat.UserID, nil
+}
+
+// respondWithAuthError answers a failed ValidateScopedToken.
+func respondWithAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingScope) {
+		respondWithError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	respondWithError(w, err.Error(), http.StatusUnauthorized)
+}
+
+func (cfg *ApiConfig) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	type CreateTokenRequest struct {
+		Name             string   `json:"name"`
+		Scopes           []string `json:"scopes"`
+		ExpiresInSeconds *int64   `json:"expires_in_seconds"`
+	}
+
+	// a token can't be used to mint more tokens
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxTokenNameLength {
+		respondWithError(w, fmt.Sprintf("Name must be between 1 and %d characters", maxTokenNameLength), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		respondWithError(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, scope := range req.Scopes {
+		if !validScopes[scope] {
+			respondWithError(w, fmt.Sprintf("Unknown scope %q", scope), http.StatusBadRequest)
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	expiresAt, err := tokenExpiry(req.ExpiresInSeconds, time.Now())
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, "Error creating token", http.StatusInternalServerError)
+		return
+	}
+
+	pat, err := cfg.DbQueries.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: auth.HashPersonalAccessToken(token),
+		TokenHint: token[len(token)-4:],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, "Error saving token", http.StatusInternalServerError)
+		return
+	}
+
+	resp := tokenFromDB(pat)
+	resp.Token = token
+	respondWithJSON(w, resp, http.StatusCreated)
+}
+
+// tokenExpiry works out when a token asked to last seconds expires. Tokens
+// without an expiry never expire.
+func tokenExpiry(seconds *int64, now time.Time) (sql.NullTime, error) {
+	if seconds == nil {
+		return sql.NullTime{}, nil
+	}
+
+	// checked in seconds, before multiplying can overflow a time.Duration
+	if *seconds <= 0 || *seconds > int64(maxTokenLifetime/time.Second) {
+		return sql.NullTime{}, fmt.Errorf("expires_in_seconds must be between 1 and %d", int64(maxTokenLifetime/time.Second))
+	}
+
+	return sql.NullTime{Time: now.Add(time.Duration(*seconds) * time.Second), Valid: true}, nil
+}
+
+func (cfg *ApiConfig) ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := cfg.DbQueries.ListPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting tokens", http.StatusInternalServerError)
+		return
+	}
+
+	tokens := []PersonalAccessToken{}
+	for _, row := range rows {
+		tokens = append(tokens, tokenFromDB(row))
+	}
+
+	respondWithJSON(w, tokens, http.StatusOK)
+}
+
+func (cfg *ApiConfig) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := cfg.DbQueries.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, "Token not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
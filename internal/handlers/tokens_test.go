@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	now := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	seconds := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name    string
+		seconds *int64
+		want    time.Time
+		wantErr bool
+	}{
+		{"no expiry", nil, time.Time{}, false},
+		{"an hour", seconds(3600), now.Add(time.Hour), false},
+		{"a year", seconds(365 * 24 * 3600), now.Add(maxTokenLifetime), false},
+		{"zero", seconds(0), time.Time{}, true},
+		{"negative", seconds(-1), time.Time{}, true},
+		{"over a year", seconds(365*24*3600 + 1), time.Time{}, true},
+		// would overflow a time.Duration
+		{"huge", seconds(10_000_000_000), time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := tokenExpiry(tt.seconds, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %v", tt.name, got.Time)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got.Valid != (tt.seconds != nil) || !got.Time.Equal(tt.want) {
+			t.Errorf("%s: got %+v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const (
+	totpIssuer            = "Chirpy"
+	twoFactorChallengeTTL = 5 * time.Minute
+	recoveryCodeCount     = 10
+)
+
+// TwoFactorChallenge is what LoginHandler returns instead of tokens when the
+// user has 2FA enabled.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+func (cfg *ApiConfig) EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	if user.TotpEnabled {
+		respondWithError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, "Error generating secret", http.StatusInternalServerError)
+		return
+	}
+
+	// enrolling again before verifying just replaces the pending secret
+	err = cfg.DbQueries.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+		ID:         user.ID,
+	})
+	if err != nil {
+		respondWithError(w, "Error saving secret", http.StatusInternalServerError)
+		return
+	}
+
+	account := user.Email
+	if user.Username.Valid {
+		account = user.Username.String
+	}
+
+	respondWithJSON(w, struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(totpIssuer, account, secret),
+	}, http.StatusOK)
+}
+
+// VerifyTOTPHandler turns 2FA on once the user proves their authenticator
+// has the secret. The recovery codes are only ever shown in this response.
+func (cfg *ApiConfig) VerifyTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type VerifyRequest struct {
+		Code string `json:"code"`
+	}
+
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req VerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	if user.TotpEnabled {
+		respondWithError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	if !user.TotpSecret.Valid {
+		respondWithError(w, "Start enrollment at /api/2fa/enroll first", http.StatusBadRequest)
+		return
+	}
+
+	step, err := auth.ValidateTOTP(user.TotpSecret.String, req.Code, time.Now())
+	if err != nil {
+		respondWithError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, "Error generating recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	if err := cfg.enableTOTP(r.Context(), user.ID, step, codes); err != nil {
+		respondWithError(w, "Error enabling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{RecoveryCodes: codes}, http.StatusOK)
+}
+
+// enableTOTP switches 2FA on and replaces the user's recovery codes. The step
+// used to verify is burnt so the same code can't also complete a login.
+func (cfg *ApiConfig) enableTOTP(ctx context.Context, userID uuid.UUID, step int64, codes []string) error {
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, auth.HashRecoveryCode(code))
+	}
+
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	if _, err := qtx.UseTOTPStep(ctx, database.UseTOTPStepParams{Step: step, ID: userID}); err != nil {
+		return fmt.Errorf("error saving TOTP step: %w", err)
+	}
+
+	if err := qtx.EnableTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("error enabling TOTP: %w", err)
+	}
+
+	if err := qtx.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	err = qtx.CreateRecoveryCodes(ctx, database.CreateRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		return fmt.Errorf("error saving recovery codes: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTPHandler turns 2FA off again. It takes a current TOTP code or a
+// recovery code, so a stolen access token alone can't strip the second
+// factor off an account.
+func (cfg *ApiConfig) DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type DisableRequest struct {
+		Code string `json:"code"`
+	}
+
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req DisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	if !user.TotpEnabled || !user.TotpSecret.Valid {
+		respondWithError(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	throttle := newLoginThrottle(user.Email, clientIP(r))
+
+	wait, err := cfg.lockedFor(r.Context(), throttle)
+	if err != nil {
+		respondWithError(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		respondWithLockout(w, wait)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), user, req.Code)
+	if err != nil {
+		respondWithError(w, "Error checking code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		cfg.recordLoginFailure(r.Context(), throttle, user.ID)
+		respondWithError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	cfg.clearLoginFailures(r.Context(), throttle)
+
+	if err := cfg.disableTOTP(r.Context(), user.ID); err != nil {
+		respondWithError(w, "Error disabling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// disableTOTP drops the user's secret and whatever recovery codes are left.
+func (cfg *ApiConfig) disableTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	if err := qtx.DisableTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("error disabling TOTP: %w", err)
+	}
+
+	if err := qtx.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Login2FAHandler completes a login started by LoginHandler. The code can be
+// either the current TOTP code or one of the user's recovery codes.
+func (cfg *ApiConfig) Login2FAHandler(w http.ResponseWriter, r *http.Request) {
+	type Login2FARequest struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+
+	var req Login2FARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID, challengeID, err := cfg.Keys.ValidateChallengeJWT(req.ChallengeToken)
+	if err != nil {
+		respondWithError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+			return
+		}
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	if !user.TotpEnabled || !user.TotpSecret.Valid {
+		respondWithError(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	// wrong codes count against the same limits as wrong passwords, or a
+	// stolen password would allow guessing codes for as long as challenge
+	// tokens can be had
+	throttle := newLoginThrottle(user.Email, clientIP(r))
+
+	wait, err := cfg.lockedFor(r.Context(), throttle)
+	if err != nil {
+		respondWithError(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		respondWithLockout(w, wait)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), user, req.Code)
+	if err != nil {
+		respondWithError(w, "Error checking code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		cfg.recordLoginFailure(r.Context(), throttle, user.ID)
+		respondWithError(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	// a challenge completes one login. Kept until the token would have
+	// expired anyway, which is never later than a TTL from now.
+	used, err := cfg.DbQueries.UseLoginChallenge(r.Context(), database.UseLoginChallengeParams{
+		ID:        challengeID,
+		ExpiresAt: time.Now().Add(twoFactorChallengeTTL),
+	})
+	if err != nil {
+		respondWithError(w, "Error checking challenge token", http.StatusInternalServerError)
+		return
+	}
+	if used == 0 {
+		respondWithError(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+		return
+	}
+
+	cfg.clearLoginFailures(r.Context(), throttle)
+
+	resp, err := cfg.startSession(r, user)
+	if err != nil {
+		respondWithError(w, "Error creating session", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, resp, http.StatusOK)
+}
+
+// checkSecondFactor accepts a TOTP code at most once, or else a recovery
+// code that hasn't been used yet.
+func (cfg *ApiConfig) checkSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	if step, err := auth.ValidateTOTP(user.TotpSecret.String, code, time.Now()); err == nil {
+		used, err := cfg.DbQueries.UseTOTPStep(ctx, database.UseTOTPStepParams{Step: step, ID: user.ID})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	used, err := cfg.DbQueries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   user.ID,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	if err != nil {
+		return false, err
+	}
+	return used == 1, nil
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+// TestTwoFactorFlow takes one account through 2FA: enrolling, turning it on,
+// logging in through a challenge with a recovery code, and turning it off
+// again. Codes and challenge tokens only ever work once.
+func TestTwoFactorFlow(t *testing.T) {
+	email := "saul@bettercallsaul.com"
+	hashed, err := auth.HashPassword("slippin-jimmy")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+	user := database.User{ID: uuid.New(), Email: email, HashedPassword: hashed}
+
+	recoveryCodes := map[string]bool{} // hash -> used
+	usedChallenges := map[string]bool{}
+	sessions := 0
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"SessionIsActive": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{true}}, nil
+		},
+		"GetUserByID": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{fakeUserRow(user)}, nil
+		},
+		"GetUser": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{fakeUserRow(user)}, nil
+		},
+		"SetTOTPSecret": func(args []driver.Value) ([][]driver.Value, error) {
+			user.TotpSecret = sql.NullString{String: args[0].(string), Valid: true}
+			user.TotpEnabled = false
+			user.TotpLastStep = 0
+			return nil, nil
+		},
+		"UseTOTPStep": func(args []driver.Value) ([][]driver.Value, error) {
+			if step := args[0].(int64); user.TotpLastStep < step {
+				user.TotpLastStep = step
+				return [][]driver.Value{{}}, nil
+			}
+			return nil, nil
+		},
+		"EnableTOTP": func(args []driver.Value) ([][]driver.Value, error) {
+			user.TotpEnabled = true
+			return nil, nil
+		},
+		"DisableTOTP": func(args []driver.Value) ([][]driver.Value, error) {
+			user.TotpSecret = sql.NullString{}
+			user.TotpEnabled = false
+			user.TotpLastStep = 0
+			return nil, nil
+		},
+		"DeleteRecoveryCodes": func(args []driver.Value) ([][]driver.Value, error) {
+			clear(recoveryCodes)
+			return nil, nil
+		},
+		"CreateRecoveryCodes": func(args []driver.Value) ([][]driver.Value, error) {
+			// the hashes arrive as a Postgres array literal
+			for _, hash := range strings.Split(strings.Trim(args[1].(string), "{}"), ",") {
+				recoveryCodes[strings.Trim(hash, `"`)] = false
+			}
+			return nil, nil
+		},
+		"UseRecoveryCode": func(args []driver.Value) ([][]driver.Value, error) {
+			hash := args[1].(string)
+			if used, ok := recoveryCodes[hash]; !ok || used {
+				return nil, nil
+			}
+			recoveryCodes[hash] = true
+			return [][]driver.Value{{}}, nil
+		},
+		"UseLoginChallenge": func(args []driver.Value) ([][]driver.Value, error) {
+			id := args[0].(string)
+			if usedChallenges[id] {
+				return nil, nil
+			}
+			usedChallenges[id] = true
+			return [][]driver.Value{{}}, nil
+		},
+		"GetLoginLocks": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"RecordLoginFailure": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{int64(1)}}, nil
+		},
+		"ClearLoginThrottle": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"CreateRefreshToken": func(args []driver.Value) ([][]driver.Value, error) {
+			sessions++
+			now := time.Now()
+			return [][]driver.Value{{
+				args[0], now, now, args[1], args[2], nil, args[3], args[4], nil, args[5], args[6], now,
+			}}, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Keys: auth.NewHMACKeySet("test-secret")}
+
+	accessToken, err := cfg.Keys.MakeSessionJWT(user.ID, uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("error creating JWT: %v", err)
+	}
+
+	call := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder, v any) {
+		t.Helper()
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("error decoding response: %v", err)
+		}
+	}
+	codeBody := func(code string) string {
+		return `{"code":"` + code + `"}`
+	}
+
+	// enroll
+	rec := call(cfg.EnrollTOTPHandler, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("enroll returned %d: %s", rec.Code, rec.Body)
+	}
+	var enrollment struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+	decode(rec, &enrollment)
+	if enrollment.Secret == "" || enrollment.Secret != user.TotpSecret.String {
+		t.Fatalf("enroll returned secret %q, saved %q", enrollment.Secret, user.TotpSecret.String)
+	}
+	if !strings.HasPrefix(enrollment.OtpauthURI, "otpauth://totp/") {
+		t.Errorf("enroll returned URI %q", enrollment.OtpauthURI)
+	}
+
+	// verify
+	if rec := call(cfg.VerifyTOTPHandler, codeBody("abcdef")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("verifying with a wrong code returned %d, want 401", rec.Code)
+	}
+	totpCode, err := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("error generating TOTP code: %v", err)
+	}
+	rec = call(cfg.VerifyTOTPHandler, codeBody(totpCode))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify returned %d: %s", rec.Code, rec.Body)
+	}
+	var verified struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	decode(rec, &verified)
+	if !user.TotpEnabled {
+		t.Fatalf("verify didn't turn 2FA on")
+	}
+	if len(verified.RecoveryCodes) != recoveryCodeCount || len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("verify handed out %d recovery codes and saved %d, want %d",
+			len(verified.RecoveryCodes), len(recoveryCodes), recoveryCodeCount)
+	}
+	for _, code := range verified.RecoveryCodes {
+		if _, ok := recoveryCodes[auth.HashRecoveryCode(code)]; !ok {
+			t.Errorf("recovery code %q wasn't saved hashed", code)
+		}
+	}
+	if rec := call(cfg.EnrollTOTPHandler, ""); rec.Code != http.StatusConflict {
+		t.Errorf("enrolling with 2FA on returned %d, want 409", rec.Code)
+	}
+
+	// the password alone only earns a challenge
+	login := func() string {
+		t.Helper()
+		rec := call(cfg.LoginHandler, `{"email":"`+email+`","password":"slippin-jimmy"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("login returned %d: %s", rec.Code, rec.Body)
+		}
+		var challenge TwoFactorChallenge
+		decode(rec, &challenge)
+		if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+			t.Fatalf("login with 2FA on returned %+v, want a challenge", challenge)
+		}
+		return challenge.ChallengeToken
+	}
+	login2FA := func(challenge, code string) *httptest.ResponseRecorder {
+		return call(cfg.Login2FAHandler, `{"challenge_token":"`+challenge+`","code":"`+code+`"}`)
+	}
+
+	challenge := login()
+	if sessions != 0 {
+		t.Fatalf("login started a session before the second factor")
+	}
+
+	// the code that turned 2FA on is used up
+	if rec := login2FA(challenge, totpCode); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reusing the verify code returned %d, want 401", rec.Code)
+	}
+
+	rec = login2FA(challenge, verified.RecoveryCodes[0])
+	if rec.Code != http.StatusOK {
+		t.Fatalf("2FA login returned %d: %s", rec.Code, rec.Body)
+	}
+	var loggedIn User
+	decode(rec, &loggedIn)
+	if loggedIn.Token == "" || loggedIn.RefreshToken == "" || sessions != 1 {
+		t.Errorf("2FA login returned %+v and started %d sessions", loggedIn, sessions)
+	}
+
+	// a challenge completes one login, even with a good code
+	if rec := login2FA(challenge, verified.RecoveryCodes[1]); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replaying a used challenge returned %d, want 401", rec.Code)
+	}
+	// and a recovery code works once
+	if rec := login2FA(login(), verified.RecoveryCodes[0]); rec.Code != http.StatusUnauthorized {
+		t.Errorf("reusing a recovery code returned %d, want 401", rec.Code)
+	}
+	if sessions != 1 {
+		t.Errorf("rejected 2FA logins started sessions, %d in total", sessions)
+	}
+
+	// disable
+	if rec := call(cfg.DisableTOTPHandler, codeBody("abcdef")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("disabling with a wrong code returned %d, want 401", rec.Code)
+	}
+	if rec := call(cfg.DisableTOTPHandler, codeBody(verified.RecoveryCodes[2])); rec.Code != http.StatusNoContent {
+		t.Fatalf("disable returned %d: %s", rec.Code, rec.Body)
+	}
+	if user.TotpEnabled || user.TotpSecret.Valid || len(recoveryCodes) != 0 {
+		t.Errorf("disable left 2FA state behind: enabled %v, secret %v, %d recovery codes",
+			user.TotpEnabled, user.TotpSecret.Valid, len(recoveryCodes))
+	}
+	if rec := call(cfg.DisableTOTPHandler, codeBody(verified.RecoveryCodes[3])); rec.Code != http.StatusBadRequest {
+		t.Errorf("disabling twice returned %d, want 400", rec.Code)
+	}
+
+	// with 2FA off the password is enough again
+	rec = call(cfg.LoginHandler, `{"email":"`+email+`","password":"slippin-jimmy"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login returned %d: %s", rec.Code, rec.Body)
+	}
+	decode(rec, &loggedIn)
+	if loggedIn.Token == "" || sessions != 2 {
+		t.Errorf("login with 2FA off returned %+v", loggedIn)
+	}
+}
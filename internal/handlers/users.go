@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+var usernameRegex = regexp.MustCompile(`^[A-Za-z0-9_]{3,20}$`)
+
+// usernames that would clash with fixed routes under /api/users
+var reservedUsernames = map[string]bool{"me": true}
+
+// Profile is the public view of a user. It never includes the email.
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Username       string    `json:"username"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	ChirpCount     int64     `json:"chirp_count"`
+}
+
+func userFromDB(user database.User) User {
+	return User{
+		ID:               user.ID,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerified,
+		PendingEmail:     user.PendingEmail.String,
+		IsChirpyRed:      user.IsChirpyRed,
+		Username:         user.Username.String,
+		DisplayName:      user.DisplayName,
+		Bio:              user.Bio,
+		TwoFactorEnabled: user.TotpEnabled,
+	}
+}
+
+// validateProfile checks the optional profile fields of a request and turns
+// them into query parameters. Fields that are nil stay NULL.
+func validateProfile(username, displayName, bio *string) (database.UpdateUserProfileParams, error) {
+	params := database.UpdateUserProfileParams{}
+
+	if username != nil {
+		if !usernameRegex.MatchString(*username) || reservedUsernames[strings.ToLower(*username)] {
+			return params, fmt.Errorf("username must be 3-20 letters, digits or underscores")
+		}
+		params.Username = sql.NullString{String: *username, Valid: true}
+	}
+
+	if displayName != nil {
+		name := strings.TrimSpace(*displayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return params, fmt.Errorf("display name can be at most %d characters", maxDisplayNameLength)
+		}
+		params.DisplayName = sql.NullString{String: name, Valid: true}
+	}
+
+	if bio != nil {
+		if utf8.RuneCountInString(*bio) > maxBioLength {
+			return params, fmt.Errorf("bio can be at most %d characters", maxBioLength)
+		}
+		params.Bio = sql.NullString{String: *bio, Valid: true}
+	}
+
+	return params, nil
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+func (cfg *ApiConfig) GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	username := r.PathValue("username")
+	if !usernameRegex.MatchString(username) {
+		respondWithError(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	profile, err := cfg.DbQueries.GetUserProfile(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Error getting profile", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, Profile{
+		ID:             profile.ID,
+		CreatedAt:      profile.CreatedAt,
+		Username:       profile.Username.String,
+		DisplayName:    profile.DisplayName,
+		Bio:            profile.Bio,
+		IsChirpyRed:    profile.IsChirpyRed,
+		FollowerCount:  profile.FollowerCount,
+		FollowingCount: profile.FollowingCount,
+		ChirpCount:     profile.ChirpCount,
+	}, http.StatusOK)
+}
+
+// PatchMeHandler updates only the fields present in the request. Changing
+// the email or password needs the current password, and a new password logs
+// out every other session.
+func (cfg *ApiConfig) PatchMeHandler(w http.ResponseWriter, r *http.Request) {
+	var req PatchUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cfg.updateMe(w, r, req)
+}
+
+// PatchUserRequest is the body of PATCH /api/users/me and PUT /api/users.
+// Fields left out stay as they are.
+type PatchUserRequest struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	CurrentPassword string  `json:"current_password"`
+	Username        *string `json:"username"`
+	DisplayName     *string `json:"display_name"`
+	Bio             *string `json:"bio"`
+}
+
+// updateMe applies req to the user the access token belongs to. A
+// current_password that's given has to be right even when nothing needs it.
+func (cfg *ApiConfig) updateMe(w http.ResponseWriter, r *http.Request, req PatchUserRequest) {
+	userID, sessionID, err := cfg.ValidateAccessTokenSession(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := validateProfile(req.Username, req.DisplayName, req.Bio)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	profile.ID = userID
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	newEmail := ""
+	if req.Email != nil && *req.Email != user.Email {
+		newEmail, err = validateEmail(*req.Email)
+		if err != nil {
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Password != nil && *req.Password == "" {
+		respondWithError(w, "Password can't be empty", http.StatusBadRequest)
+		return
+	}
+
+	if newEmail != "" || req.Password != nil || req.CurrentPassword != "" {
+		// a wrong current password counts as a failed login, or this would
+		// be a way around the login throttle
+		throttle := newLoginThrottle(user.Email, clientIP(r))
+
+		wait, err := cfg.lockedFor(r.Context(), throttle)
+		if err != nil {
+			respondWithError(w, "Error checking login attempts", http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			respondWithLockout(w, wait)
+			return
+		}
+
+		if err := auth.CheckPasswordHash(user.HashedPassword, req.CurrentPassword); err != nil {
+			cfg.recordLoginFailure(r.Context(), throttle, user.ID)
+			respondWithError(w, "Current password is incorrect", http.StatusForbidden)
+			return
+		}
+	}
+
+	hashedPassword := ""
+	if req.Password != nil {
+		hashedPassword, err = auth.HashPassword(*req.Password)
+		if err != nil {
+			respondWithError(w, "Couldn't hash a password", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	updated, err := cfg.patchUser(r.Context(), profile, hashedPassword, newEmail, sessionID)
+	if err != nil {
+		if isUniqueViolation(err, "users_username_lower_idx") {
+			respondWithError(w, "Username is already taken", http.StatusConflict)
+			return
+		}
+		respondWithError(w, "Error updating user", http.StatusInternalServerError)
+		return
+	}
+
+	if newEmail != "" {
+		cfg.sendEmailVerification(userID, newEmail)
+	}
+
+	respondWithJSON(w, userFromDB(updated), http.StatusOK)
+}
+
+// patchUser applies a PATCH in one transaction. Empty hashedPassword and
+// newEmail leave those alone. A password change revokes every session except
+// keepSession, and any reset links still in the user's inbox.
+func (cfg *ApiConfig) patchUser(ctx context.Context, profile database.UpdateUserProfileParams, hashedPassword, newEmail string, keepSession uuid.UUID) (database.User, error) {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	if hashedPassword != "" {
+		err = qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+			ID:             profile.ID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			return database.User{}, fmt.Errorf("error updating password: %w", err)
+		}
+
+		err = qtx.RevokeOtherRefreshTokens(ctx, database.RevokeOtherRefreshTokensParams{
+			UserID:   profile.ID,
+			FamilyID: keepSession,
+		})
+		if err != nil {
+			return database.User{}, fmt.Errorf("error revoking sessions: %w", err)
+		}
+
+		if err := qtx.ExpirePasswordResetTokens(ctx, profile.ID); err != nil {
+			return database.User{}, fmt.Errorf("error expiring reset tokens: %w", err)
+		}
+
+		err = qtx.CreateSecurityEvent(ctx, database.CreateSecurityEventParams{
+			UserID:    profile.ID,
+			EventType: securityEventPasswordChanged,
+			Details:   "password changed; other sessions revoked",
+		})
+		if err != nil {
+			return database.User{}, fmt.Errorf("error recording security event: %w", err)
+		}
+	}
+
+	// a new email only takes effect once it's been verified
+	if newEmail != "" {
+		err = qtx.SetPendingEmail(ctx, database.SetPendingEmailParams{
+			ID:           profile.ID,
+			PendingEmail: sql.NullString{String: newEmail, Valid: true},
+		})
+		if err != nil {
+			return database.User{}, fmt.Errorf("error setting pending email: %w", err)
+		}
+	}
+
+	// runs last so the returned user includes the changes above
+	user, err := qtx.UpdateUserProfile(ctx, profile)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.User{}, fmt.Errorf("error committing update: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+func TestValidateProfile(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name        string
+		username    *string
+		displayName *string
+		bio         *string
+		wantErr     bool
+	}{
+		{"nothing", nil, nil, nil, false},
+		{"all fields", str("heisenberg"), str("Walter White"), str("Chemistry teacher"), false},
+		{"underscores and digits", str("cap_n_cook99"), nil, nil, false},
+		{"too short", str("ww"), nil, nil, true},
+		{"too long", str(strings.Repeat("w", 21)), nil, nil, true},
+		{"punctuation", str("walter.white"), nil, nil, true},
+		{"reserved", str("ME"), nil, nil, true},
+		{"long display name", nil, str(strings.Repeat("é", maxDisplayNameLength+1)), nil, true},
+		{"long bio", nil, nil, str(strings.Repeat("é", maxBioLength+1)), true},
+	}
+
+	for _, tt := range tests {
+		_, err := validateProfile(tt.username, tt.displayName, tt.bio)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateProfileTrimsDisplayName(t *testing.T) {
+	name := "  Walter White \n"
+	params, err := validateProfile(nil, &name, nil)
+	if err != nil {
+		t.Fatalf("validateProfile: %v", err)
+	}
+	if !params.DisplayName.Valid || params.DisplayName.String != "Walter White" {
+		t.Errorf("display name = %+v, want Walter White", params.DisplayName)
+	}
+	if params.Username.Valid || params.Bio.Valid {
+		t.Errorf("fields left out of the request should stay NULL")
+	}
+}
+
+// A name that can't be a username isn't looked up.
+func TestGetProfileRejectsInvalidUsername(t *testing.T) {
+	cfg := &ApiConfig{}
+	for _, username := range []string{"ww", "walter.white", "walter%20white"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/x", nil)
+		req.SetPathValue("username", username)
+		rec := httptest.NewRecorder()
+
+		cfg.GetProfileHandler(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%q: status = %d, want 404", username, rec.Code)
+		}
+	}
+}
+
+// PUT /api/users used to set whatever password it was sent. It now needs the
+// current one, like PATCH /api/users/me.
+func TestUpdateUserNeedsCurrentPassword(t *testing.T) {
+	userID, sessionID := uuid.New(), uuid.New()
+	hashed, err := auth.HashPassword("heisenberg")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"SessionIsActive": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{true}}, nil
+		},
+		"GetUserByID": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{fakeUserRow(database.User{ID: userID, Email: "walt@breakingbad.com", HashedPassword: hashed})}, nil
+		},
+		"GetLoginLocks": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"RecordLoginFailure": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{int64(1)}}, nil
+		},
+		// UpdateUserPassword isn't here, so calling it fails the test
+	})
+
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Keys: auth.NewHMACKeySet("test-secret")}
+	token, err := cfg.Keys.MakeSessionJWT(userID, sessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeSessionJWT: %v", err)
+	}
+
+	for _, body := range []string{
+		`{"password":"say-my-name"}`,
+		`{"password":"say-my-name","current_password":"wrong"}`,
+		`{"email":"heisenberg@breakingbad.com","password":"say-my-name"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/users", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		cfg.UpdateUserHandler(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403", body, rec.Code)
+		}
+	}
+}
+
+// TestUpdateUserKeepsOldContract sends PUT bodies the way older clients do.
+// Profile changes go through without current_password, and resending the
+// current email and password alongside them changes neither.
+func TestUpdateUserKeepsOldContract(t *testing.T) {
+	userID, sessionID := uuid.New(), uuid.New()
+	hashed, err := auth.HashPassword("heisenberg")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+	user := database.User{ID: userID, Email: "walt@breakingbad.com", HashedPassword: hashed, DisplayName: "Walter"}
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"SessionIsActive": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{{true}}, nil
+		},
+		"GetUserByID": func(args []driver.Value) ([][]driver.Value, error) {
+			return [][]driver.Value{fakeUserRow(user)}, nil
+		},
+		"GetLoginLocks": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"UpdateUserProfile": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[1] != nil {
+				user.DisplayName = args[1].(string)
+			}
+			return [][]driver.Value{fakeUserRow(user)}, nil
+		},
+		// UpdateUserPassword and SetPendingEmail aren't here, so calling
+		// either fails the test
+	})
+
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Keys: auth.NewHMACKeySet("test-secret")}
+	token, err := cfg.Keys.MakeSessionJWT(userID, sessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeSessionJWT: %v", err)
+	}
+
+	for _, tc := range []struct {
+		body        string
+		displayName string
+	}{
+		{`{"display_name":"Heisenberg"}`, "Heisenberg"},
+		{`{"email":"","password":"","display_name":"Mr. White"}`, "Mr. White"},
+		{`{"email":"walt@breakingbad.com","password":"heisenberg","display_name":"Walt"}`, "Walt"},
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/users", strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		cfg.UpdateUserHandler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200: %s", tc.body, rec.Code, rec.Body)
+			continue
+		}
+		if user.DisplayName != tc.displayName {
+			t.Errorf("%s: display name = %q, want %q", tc.body, user.DisplayName, tc.displayName)
+		}
+		if rec.Header().Get("Deprecation") == "" {
+			t.Errorf("%s: no Deprecation header", tc.body)
+		}
+	}
+}
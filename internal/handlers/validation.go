@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const (
+	maxChirpLength    = 140
+	maxRedChirpLength = 280
+)
+
+// ValidationError is the body of every 400 caused by invalid chirp content.
+// Code is stable so clients can react to it without parsing the message.
+type ValidationError struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	MaxLength int    `json:"max_length,omitempty"`
+	// entitlement is set when a higher plan would have allowed the chirp
+	entitlement *EntitlementError
+}
+
+// chirpRule inspects or rewrites a chirp body before it is stored.
+type chirpRule func(cfg *ApiConfig, body string, author database.User) (string, *ValidationError)
+
+// chirpRules run in order; the first error stops the pipeline.
+var chirpRules = []chirpRule{
+	requireChirpBody,
+	limitChirpLength,
+	cleanChirpBody,
+}
+
+func (cfg *ApiConfig) validateChirp(body string, author database.User) (string, *ValidationError) {
+	for _, rule := range chirpRules {
+		var verr *ValidationError
+		body, verr = rule(cfg, body, author)
+		if verr != nil {
+			return "", verr
+		}
+	}
+	return body, nil
+}
+
+func requireChirpBody(_ *ApiConfig, body string, _ database.User) (string, *ValidationError) {
+	if strings.TrimSpace(body) == "" {
+		return "", &ValidationError{Error: "Chirp can't be empty", Code: "chirp_empty"}
+	}
+	return body, nil
+}
+
+func maxChirpLengthFor(author database.User) int {
+	if hasFeature(author, FeatureLongChirps) {
+		return maxRedChirpLength
+	}
+	return maxChirpLength
+}
+
+func limitChirpLength(_ *ApiConfig, body string, author database.User) (string, *ValidationError) {
+	length := utf8.RuneCountInString(body)
+	limit := maxChirpLengthFor(author)
+	if length <= limit {
+		return body, nil
+	}
+
+	if length <= maxRedChirpLength {
+		if eerr := checkFeature(author, FeatureLongChirps); eerr != nil {
+			return "", &ValidationError{Error: eerr.Error, Code: eerr.Code, MaxLength: limit, entitlement: eerr}
+		}
+	}
+	return "", &ValidationError{Error: "Chirp is too long", Code: "chirp_too_long", MaxLength: limit}
+}
+
+func cleanChirpBody(cfg *ApiConfig, body string, _ database.User) (string, *ValidationError) {
+	return cfg.Profanity.Clean(body), nil
+}
+
+func respondWithValidationError(w http.ResponseWriter, verr *ValidationError) {
+	if verr.entitlement != nil {
+		respondWithEntitlementError(w, verr.entitlement)
+		return
+	}
+	respondWithJSON(w, verr, http.StatusBadRequest)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/moderation"
+)
+
+func TestValidateChirp(t *testing.T) {
+	cfg := &ApiConfig{Profanity: moderation.NewFilter([]string{"kerfuffle"})}
+	regular := database.User{}
+	red := database.User{IsChirpyRed: true}
+
+	cases := []struct {
+		name   string
+		body   string
+		author database.User
+		want   string
+		code   string
+	}{
+		{"empty", "   ", regular, "", "chirp_empty"},
+		{"filtered", "what a Kerfuffle today", regular, "what a **** today", ""},
+		{"red only length", strings.Repeat("a", 141), regular, "", "plan_required"},
+		{"too long", strings.Repeat("a", 281), regular, "", "chirp_too_long"},
+		{"red limit", strings.Repeat("a", 200), red, strings.Repeat("a", 200), ""},
+		{"red too long", strings.Repeat("a", 281), red, "", "chirp_too_long"},
+	}
+
+	for _, c := range cases {
+		got, verr := cfg.validateChirp(c.body, c.author)
+		if c.code != "" {
+			if verr == nil || verr.Code != c.code {
+				t.Errorf("%s: expected error code %q, got %+v", c.name, c.code, verr)
+			}
+			continue
+		}
+		if verr != nil {
+			t.Errorf("%s: unexpected error %+v", c.name, verr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: expected body %q, got %q", c.name, c.want, got)
+		}
+	}
+}
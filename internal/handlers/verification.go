@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/mailer"
+)
+
+const (
+	emailVerificationTTL = 24 * time.Hour
+	maxEmailLength       = 254
+)
+
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// validateEmail accepts a bare address like walt@breakingbad.com. Display
+// names and anything else net/mail would tolerate are rejected.
+func validateEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" || len(email) > maxEmailLength {
+		return "", fmt.Errorf("invalid email address")
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("invalid email address")
+	}
+
+	at := strings.LastIndex(email, "@")
+	if !strings.Contains(email[at+1:], ".") {
+		return "", fmt.Errorf("invalid email address")
+	}
+
+	return email, nil
+}
+
+// sendEmailVerification mails a link that proves the user owns email. It
+// runs in the background, a slow mail server shouldn't hold up signups.
+func (cfg *ApiConfig) sendEmailVerification(userID uuid.UUID, email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := cfg.mailEmailVerification(ctx, userID, email); err != nil {
+			log.Printf("Error sending email verification to user %v: %v", userID, err)
+		}
+	}()
+}
+
+func (cfg *ApiConfig) mailEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := auth.MakeToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.DbQueries.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving verification token: %w", err)
+	}
+
+	link := cfg.BaseURL + "/verify-email?token=" + url.QueryEscape(token)
+
+	return cfg.Mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email for Chirpy",
+		Body: fmt.Sprintf("Please confirm this is your email address by opening this link "+
+			"within the next 24 hours:\n\n%s\n\n"+
+			"If you didn't sign up for Chirpy, you can ignore this email.\n", link),
+	})
+}
+
+// VerifyEmailPageHandler is where the mailed verification link lands. Its
+// button posts the token to ConfirmEmailHandler.
+func (cfg *ApiConfig) VerifyEmailPageHandler(w http.ResponseWriter, r *http.Request) {
+	renderTokenPage(w, r, tokenPage{
+		Title:    "Confirm your email",
+		Endpoint: "/api/email-verification/confirm",
+		Button:   "Confirm email",
+		Done:     "Thanks, your email is confirmed.",
+	})
+}
+
+// ConfirmEmailHandler marks the address in the token as verified. For an
+// email change that's when the new address replaces the old one.
+func (cfg *ApiConfig) ConfirmEmailHandler(w http.ResponseWriter, r *http.Request) {
+	type ConfirmRequest struct {
+		Token string `json:"token"`
+	}
+
+	var req ConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := cfg.confirmEmail(r.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, errInvalidVerificationToken) {
+			respondWithError(w, "Invalid or expired verification token", http.StatusBadRequest)
+			return
+		}
+		if isUniqueViolation(err, "users_email_key") {
+			respondWithError(w, "Email is already in use", http.StatusConflict)
+			return
+		}
+		respondWithError(w, "Error verifying email", http.StatusInternalServerError)
+		return
+	}
+
+	respondWithJSON(w, userFromDB(user), http.StatusOK)
+}
+
+// confirmEmail uses up token and verifies its address in one transaction, so
+// a token is only spent if the address really gets verified.
+func (cfg *ApiConfig) confirmEmail(ctx context.Context, token string) (database.User, error) {
+	tx, err := cfg.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.DbQueries.WithTx(tx)
+
+	verified, err := qtx.UseEmailVerificationToken(ctx, auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errInvalidVerificationToken
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("error using verification token: %w", err)
+	}
+
+	user, err := qtx.VerifyUserEmail(ctx, database.VerifyUserEmailParams{
+		Email: verified.Email,
+		ID:    verified.UserID,
+	})
+	// the user moved on to yet another address since this link was sent
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, errInvalidVerificationToken
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("error verifying email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.User{}, fmt.Errorf("error committing email verification: %w", err)
+	}
+
+	return user, nil
+}
+
+// ResendVerificationHandler sends a new link for the pending email, or for
+// the current one if it was never verified.
+func (cfg *ApiConfig) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.ValidateAccessToken(r.Context(), r.Header)
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case user.PendingEmail.Valid:
+		cfg.sendEmailVerification(user.ID, user.PendingEmail.String)
+	case !user.EmailVerified:
+		cfg.sendEmailVerification(user.ID, user.Email)
+	default:
+		respondWithError(w, "Email is already verified", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
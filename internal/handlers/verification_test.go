@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+	"github.com/realquiller/chirpy_server/internal/mailer"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{"walt@breakingbad.com", "walt@breakingbad.com", false},
+		{"  walt@breakingbad.com ", "walt@breakingbad.com", false},
+		{"jesse.pinkman+chirpy@example.co.uk", "jesse.pinkman+chirpy@example.co.uk", false},
+		{"", "", true},
+		{"walt", "", true},
+		{"walt@", "", true},
+		{"walt@localhost", "", true},
+		{"Walter White <walt@breakingbad.com>", "", true},
+		{"walt@breakingbad.com, jesse@breakingbad.com", "", true},
+		{"walt@breakingbad.com\r\nBcc: x@y.com", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := validateEmail(tt.email)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("validateEmail(%q) = %q, expected error", tt.email, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("validateEmail(%q): unexpected error %v", tt.email, err)
+		} else if got != tt.want {
+			t.Errorf("validateEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+// The mailed link opens a page that posts the token back, following the
+// link alone doesn't use it up.
+func TestVerificationLinkOpensPage(t *testing.T) {
+	var stored string
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"CreateEmailVerificationToken": func(args []driver.Value) ([][]driver.Value, error) {
+			stored = args[0].(string)
+			return nil, nil
+		},
+	})
+
+	mail := mailer.NewMemoryMailer()
+	cfg := &ApiConfig{Db: db, DbQueries: queries, Mailer: mail, BaseURL: "https://chirpy.example.com"}
+
+	if err := cfg.mailEmailVerification(context.Background(), uuid.New(), "jesse@breakingbad.com"); err != nil {
+		t.Fatalf("mailEmailVerification: %v", err)
+	}
+
+	messages := mail.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected one email, got %d", len(messages))
+	}
+	link, err := url.Parse(regexp.MustCompile(`https?://\S+`).FindString(messages[0].Body))
+	if err != nil || link.Path != "/verify-email" {
+		t.Fatalf("email doesn't link to /verify-email: %q", messages[0].Body)
+	}
+	token := link.Query().Get("token")
+	if auth.HashToken(token) != stored {
+		t.Fatalf("mailed token doesn't match the stored hash")
+	}
+
+	rec := httptest.NewRecorder()
+	cfg.VerifyEmailPageHandler(rec, httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), token) {
+		t.Errorf("verification page: status %d, token in page %v", rec.Code, strings.Contains(rec.Body.String(), token))
+	}
+}
+
+// TestConfirmEmailIsAtomic confirms an email change whose new address was
+// taken in the meantime. The token has to survive that, so the link still
+// works once the address is free again, and then only once.
+func TestConfirmEmailIsAtomic(t *testing.T) {
+	user := database.User{ID: uuid.New(), Email: "jesse@breakingbad.com"}
+	newEmail := "capncook@breakingbad.com"
+	token := "confirm-me"
+
+	used, spending := false, false
+	emailTaken := true
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		"UseEmailVerificationToken": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[0] != auth.HashToken(token) || used {
+				return nil, nil
+			}
+			used, spending = true, true
+			return [][]driver.Value{{user.ID.String(), newEmail}}, nil
+		},
+		"VerifyUserEmail": func(args []driver.Value) ([][]driver.Value, error) {
+			if emailTaken {
+				return nil, &pq.Error{Code: "23505", Constraint: "users_email_key"}
+			}
+			user.Email = args[0].(string)
+			user.EmailVerified = true
+			return [][]driver.Value{fakeUserRow(user)}, nil
+		},
+		"Commit": func(args []driver.Value) ([][]driver.Value, error) {
+			spending = false
+			return nil, nil
+		},
+		"Rollback": func(args []driver.Value) ([][]driver.Value, error) {
+			if spending {
+				used, spending = false, false
+			}
+			return nil, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries}
+
+	confirm := func() int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/email-verification/confirm", strings.NewReader(`{"token":"`+token+`"}`))
+		cfg.ConfirmEmailHandler(rec, req)
+		return rec.Code
+	}
+
+	if code := confirm(); code != http.StatusConflict {
+		t.Fatalf("confirming a taken address returned %d, want 409", code)
+	}
+	if used {
+		t.Fatalf("a failed confirmation used up the token")
+	}
+
+	emailTaken = false
+	if code := confirm(); code != http.StatusOK {
+		t.Fatalf("confirming returned %d, want 200", code)
+	}
+	if user.Email != newEmail || !user.EmailVerified {
+		t.Errorf("user is %s, verified %v, want %s verified", user.Email, user.EmailVerified, newEmail)
+	}
+
+	if code := confirm(); code != http.StatusBadRequest {
+		t.Errorf("confirming twice returned %d, want 400", code)
+	}
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/realquiller/chirpy_server/internal/auth"
+	"github.com/realquiller/chirpy_server/internal/database"
+)
+
+const maxWebhookBodySize = 1 << 20
+
+// webhookEventLease is how long an event may sit in "processing". After that
+// it's taken to be stuck, say because the server died while handling it, and
+// the next delivery or an admin replay picks it up again.
+const webhookEventLease = 5 * time.Minute
+
+// Every webhook is logged in webhook_events with one of these statuses.
+// Events in "processing" are being handled right now, unless they've been
+// there longer than webhookEventLease. "Ignored" ones were for events we
+// don't act on.
+const (
+	webhookStatusProcessing = "processing"
+	webhookStatusProcessed  = "processed"
+	webhookStatusIgnored    = "ignored"
+	webhookStatusFailed     = "failed"
+)
+
+var (
+	errWebhookBadPayload   = errors.New("invalid webhook payload")
+	errWebhookUserNotFound = errors.New("user not found")
+)
+
+// polkaEvent is the body Polka sends. ID is optional, see webhookEventID.
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID           string     `json:"user_id"`
+		Plan             string     `json:"plan"`
+		CurrentPeriodEnd *time.Time `json:"current_period_end"`
+	} `json:"data"`
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int32           `json:"attempts"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+func webhookEventFromDB(event database.WebhookEvent) WebhookEvent {
+	e := WebhookEvent{
+		ID:        event.ID,
+		CreatedAt: event.CreatedAt,
+		UpdatedAt: event.UpdatedAt,
+		EventID:   event.EventID,
+		EventType: event.EventType,
+		Payload:   json.RawMessage(event.Payload),
+		Status:    event.Status,
+		Error:     event.Error.String,
+		Attempts:  event.Attempts,
+	}
+	if event.ProcessedAt.Valid {
+		e.ProcessedAt = &event.ProcessedAt.Time
+	}
+	return e
+}
+
+// WebhookUpgradeUserHandler takes events from Polka. Each one is logged
+// before it's acted on, and an event we've already handled is acknowledged
+// without running it again. A failed event is retried when Polka resends it.
+func (cfg *ApiConfig) WebhookUpgradeUserHandler(w http.ResponseWriter, r *http.Request) {
+	// the signature covers the raw bytes, so read them before decoding
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		respondWithError(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+
+	err = auth.VerifyWebhook(r.Header, body, cfg.PolkaSecrets, auth.DefaultWebhookTolerance, time.Now())
+	if err != nil {
+		respondWithError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var webhook polkaEvent
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		respondWithError(w, "Error decoding JSON in WebhookUpgrade", http.StatusBadRequest)
+		return
+	}
+
+	eventID := webhookEventID(webhook, body)
+	event, err := cfg.DbQueries.ClaimWebhookEvent(r.Context(), database.ClaimWebhookEventParams{
+		EventID:      eventID,
+		EventType:    webhook.Event,
+		Payload:      string(body),
+		LeaseSeconds: webhookEventLease.Seconds(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.respondToDuplicateWebhook(w, r, eventID)
+		return
+	}
+	if err != nil {
+		respondWithError(w, "Error recording webhook event", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := cfg.processWebhookEvent(r.Context(), event); err != nil {
+		switch {
+		case errors.Is(err, errWebhookBadPayload):
+			respondWithError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, errWebhookUserNotFound):
+			respondWithError(w, "User not found", http.StatusNotFound)
+		case errors.Is(err, errWebhookSubscriptionNotFound):
+			respondWithError(w, "Subscription not found", http.StatusNotFound)
+		default:
+			respondWithError(w, "Error processing webhook event", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondToDuplicateWebhook answers an event we've already seen. Once it's
+// done it's acknowledged; while another delivery is still working on it the
+// sender is told to try again, since that attempt may yet fail.
+func (cfg *ApiConfig) respondToDuplicateWebhook(w http.ResponseWriter, r *http.Request, eventID string) {
+	status, err := cfg.DbQueries.GetWebhookEventStatus(r.Context(), eventID)
+	if err != nil {
+		respondWithError(w, "Error recording webhook event", http.StatusInternalServerError)
+		return
+	}
+
+	if status == webhookStatusProcessing {
+		w.Header().Set("Retry-After", strconv.Itoa(int(webhookEventLease.Seconds())))
+		respondWithError(w, "Webhook event is still being processed", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhookEventID is the key duplicates are found by. Events without an ID are
+// keyed by their body alone, so a retry is recognised however late it comes
+// and however often it's signed again. The price is that an identical event
+// sent again on purpose is taken for a retry too. Renewals carry their period
+// end, so only upgrades without one, cancellations and downgrades can repeat
+// like that.
+func webhookEventID(webhook polkaEvent, body []byte) string {
+	if webhook.ID != "" {
+		return webhook.ID
+	}
+	return "sha256:" + auth.HashToken(string(body))
+}
+
+// processWebhookEvent acts on a claimed event and records how it went. The
+// returned error is the one from acting on the event.
+func (cfg *ApiConfig) processWebhookEvent(ctx context.Context, event database.WebhookEvent) (database.WebhookEvent, error) {
+	status := webhookStatusProcessed
+	handled, procErr := cfg.applyWebhookEvent(ctx, []byte(event.Payload))
+	if !handled {
+		status = webhookStatusIgnored
+	}
+
+	eventErr := sql.NullString{}
+	if procErr != nil {
+		status = webhookStatusFailed
+		eventErr = sql.NullString{String: procErr.Error(), Valid: true}
+	}
+
+	// recording the outcome must not hang on a request that was cancelled
+	// halfway, or the event would be stuck in processing
+	finished, err := cfg.DbQueries.FinishWebhookEvent(context.WithoutCancel(ctx), database.FinishWebhookEventParams{
+		ID:     event.ID,
+		Status: status,
+		Error:  eventErr,
+	})
+	if err != nil {
+		log.Printf("Error recording outcome of webhook event %v: %v", event.ID, err)
+		if procErr == nil {
+			procErr = fmt.Errorf("error recording webhook event: %w", err)
+		}
+		return event, procErr
+	}
+
+	return finished, procErr
+}
+
+// applyWebhookEvent does what the event asks. It reports false for event
+// types we don't act on.
+func (cfg *ApiConfig) applyWebhookEvent(ctx context.Context, payload []byte) (bool, error) {
+	var webhook polkaEvent
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return false, errWebhookBadPayload
+	}
+
+	if !isSubscriptionEvent(webhook.Event) {
+		return false, nil
+	}
+
+	userID, err := uuid.Parse(webhook.Data.UserID)
+	if err != nil {
+		return true, fmt.Errorf("%w: invalid user ID format", errWebhookBadPayload)
+	}
+
+	return true, cfg.applySubscriptionEvent(ctx, webhook, userID)
+}
+
+// ListFailedWebhookEventsHandler lists failed events, and stuck ones that
+// have been processing for longer than webhookEventLease.
+func (cfg *ApiConfig) ListFailedWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.DbQueries.ListFailedWebhookEvents(r.Context(), webhookEventLease.Seconds())
+	if err != nil {
+		respondWithError(w, "Error getting webhook events", http.StatusInternalServerError)
+		return
+	}
+
+	events := []WebhookEvent{}
+	for _, row := range rows {
+		events = append(events, webhookEventFromDB(row))
+	}
+
+	respondWithJSON(w, events, http.StatusOK)
+}
+
+// ReplayWebhookEventHandler processes one failed or stuck event again and returns it
+// with its new status.
+func (cfg *ApiConfig) ReplayWebhookEventHandler(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, "Invalid event ID", http.StatusBadRequest)
+		return
+	}
+
+	event, err := cfg.DbQueries.RetryWebhookEvent(r.Context(), database.RetryWebhookEventParams{
+		ID:           eventID,
+		LeaseSeconds: webhookEventLease.Seconds(),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, "Failed event not found", http.StatusNotFound)
+			return
+		}
+		respondWithError(w, "Error getting webhook event", http.StatusInternalServerError)
+		return
+	}
+
+	// the outcome is in the returned event, including any error
+	event, _ = cfg.processWebhookEvent(r.Context(), event)
+
+	respondWithJSON(w, webhookEventFromDB(event), http.StatusOK)
+}
+
+// ReplayFailedWebhookEventsHandler processes every failed or stuck event again and
+// returns them with their new statuses.
+func (cfg *ApiConfig) ReplayFailedWebhookEventsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := cfg.DbQueries.ListFailedWebhookEvents(r.Context(), webhookEventLease.Seconds())
+	if err != nil {
+		respondWithError(w, "Error getting webhook events", http.StatusInternalServerError)
+		return
+	}
+
+	events := []WebhookEvent{}
+	for _, row := range rows {
+		event, err := cfg.DbQueries.RetryWebhookEvent(r.Context(), database.RetryWebhookEventParams{
+			ID:           row.ID,
+			LeaseSeconds: webhookEventLease.Seconds(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// Polka's own retry got to it first
+			continue
+		}
+		if err != nil {
+			respondWithError(w, "Error getting webhook event", http.StatusInternalServerError)
+			return
+		}
+
+		event, _ = cfg.processWebhookEvent(r.Context(), event)
+		events = append(events, webhookEventFromDB(event))
+	}
+
+	respondWithJSON(w, events, http.StatusOK)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/realquiller/chirpy_server/internal/auth"
+)
+
+// None of these cases get as far as the database.
+func TestApplyWebhookEventWithoutUpgrade(t *testing.T) {
+	cfg := &ApiConfig{}
+
+	tests := []struct {
+		name        string
+		payload     string
+		wantHandled bool
+		wantErr     error
+	}{
+		{"other event", `{"event":"user.payment_failed","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`, false, nil},
+		{"not json", `user.upgraded`, false, errWebhookBadPayload},
+		{"bad user id", `{"event":"user.upgraded","data":{"user_id":"walter"}}`, true, errWebhookBadPayload},
+	}
+
+	for _, tt := range tests {
+		handled, err := cfg.applyWebhookEvent(context.Background(), []byte(tt.payload))
+		if handled != tt.wantHandled {
+			t.Errorf("%s: handled = %v, want %v", tt.name, handled, tt.wantHandled)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookEventID(t *testing.T) {
+	body := []byte(`{"event":"user.renewed","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c","current_period_end":"2024-06-01T00:00:00Z"}}`)
+
+	withID := polkaEvent{ID: "evt_123"}
+	if got := webhookEventID(withID, body); got != "evt_123" {
+		t.Errorf("event with an ID got %q", got)
+	}
+
+	// a retry is signed again, but its body doesn't change
+	first := webhookEventID(polkaEvent{}, body)
+	if retry := webhookEventID(polkaEvent{}, body); retry != first {
+		t.Errorf("a resend of the same event got a new ID")
+	}
+	next := []byte(`{"event":"user.renewed","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c","current_period_end":"2024-07-01T00:00:00Z"}}`)
+	if got := webhookEventID(polkaEvent{}, next); got == first {
+		t.Errorf("renewals for different periods share ID %q", first)
+	}
+}
+
+// TestDuplicateWebhookEvent resends an event we've already claimed. It's
+// acknowledged once it's been handled, but while the first delivery is still
+// processing it Polka is asked to come back later.
+func TestDuplicateWebhookEvent(t *testing.T) {
+	secret := "polka-secret"
+	body := []byte(`{"id":"evt_123","event":"user.payment_failed","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	status := webhookStatusProcessing
+
+	db, queries := newFakeDB(t, map[string]fakeQuery{
+		// the event is already there, so nothing gets claimed
+		"ClaimWebhookEvent": func(args []driver.Value) ([][]driver.Value, error) {
+			return nil, nil
+		},
+		"GetWebhookEventStatus": func(args []driver.Value) ([][]driver.Value, error) {
+			if args[0] != "evt_123" {
+				t.Errorf("looked up event %v, want evt_123", args[0])
+			}
+			return [][]driver.Value{{status}}, nil
+		},
+	})
+	cfg := &ApiConfig{Db: db, DbQueries: queries, PolkaSecrets: []string{secret}}
+
+	deliver := func() *httptest.ResponseRecorder {
+		now := time.Now()
+		req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", bytes.NewReader(body))
+		req.Header.Set(auth.WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+		req.Header.Set(auth.WebhookSignatureHeader, auth.SignWebhook(secret, now, body))
+		rec := httptest.NewRecorder()
+		cfg.WebhookUpgradeUserHandler(rec, req)
+		return rec
+	}
+
+	rec := deliver()
+	if rec.Code != http.StatusConflict {
+		t.Errorf("duplicate of an event in processing got %d, want 409", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Errorf("409 without Retry-After")
+	}
+
+	for _, status = range []string{webhookStatusProcessed, webhookStatusIgnored} {
+		if rec := deliver(); rec.Code != http.StatusNoContent {
+			t.Errorf("duplicate of a %s event got %d, want 204", status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer writes each message to its own .eml file in Dir, which any mail
+// client can open. It's meant for local development.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{Dir: dir, From: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := format(m.From, msg, now)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("error creating mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.NewString()[:8])
+	if err := os.WriteFile(filepath.Join(m.Dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("error writing mail: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package mailer sends the transactional emails Chirpy needs, like password
+// resets. Production uses SMTP, dev and tests use the file and memory mailers.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders msg as a plain text RFC 5322 message.
+func format(from string, msg Message, date time.Time) ([]byte, error) {
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.Body)
+
+	return buf.Bytes(), nil
+}
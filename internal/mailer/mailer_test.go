@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	msg := Message{To: "walt@breakingbad.com", Subject: "Réinitialiser", Body: "Hello"}
+
+	data, err := format("Chirpy <no-reply@chirpy.dev>", msg, time.Unix(0, 0).UTC())
+	if err != nil {
+		t.Fatalf("format: %v", err)
+	}
+
+	got := string(data)
+	for _, want := range []string{
+		"From: Chirpy <no-reply@chirpy.dev>\r\n",
+		"To: walt@breakingbad.com\r\n",
+		"Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nHello",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("message is missing %q:\n%s", want, got)
+		}
+	}
+
+	// a recipient with a newline would let the caller inject headers
+	if _, err := format("no-reply@chirpy.dev", Message{To: "a@b.com\r\nBcc: c@d.com"}, time.Now()); err == nil {
+		t.Errorf("expected error for invalid recipient")
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+	var _ Mailer = m
+
+	for _, to := range []string{"a@example.com", "b@example.com"} {
+		if err := m.Send(context.Background(), Message{To: to}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	msgs := m.Messages()
+	if len(msgs) != 2 || msgs[0].To != "a@example.com" || msgs[1].To != "b@example.com" {
+		t.Errorf("got messages %+v", msgs)
+	}
+
+	msgs[0].To = "changed"
+	if m.Messages()[0].To != "a@example.com" {
+		t.Errorf("Messages doesn't return a copy")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := NewFileMailer(dir, "no-reply@chirpy.dev")
+	var _ Mailer = m
+
+	if err := m.Send(context.Background(), Message{To: "a@example.com", Subject: "Hi", Body: "token"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v (%v)", files, err)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("error reading mail: %v", err)
+	}
+	if !strings.HasSuffix(string(data), "\r\n\r\ntoken") {
+		t.Errorf("unexpected mail contents:\n%s", data)
+	}
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	if _, err := NewSMTPMailer("localhost", "no-reply@chirpy.dev", "", ""); err == nil {
+		t.Errorf("expected error for address without a port")
+	}
+
+	m, err := NewSMTPMailer("localhost:1025", "no-reply@chirpy.dev", "", "")
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+	var _ Mailer = m
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Send(ctx, Message{To: "a@example.com"}); err == nil {
+		t.Errorf("expected error for cancelled context")
+	}
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps every message it's asked to send. Tests use it to read
+// the links a handler mailed out.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of everything sent so far, oldest first.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
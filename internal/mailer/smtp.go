@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+type SMTPMailer struct {
+	Addr string
+	From string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer sends through the server at addr (host:port). Username and
+// password may be empty for servers that don't need authentication.
+func NewSMTPMailer(addr, from, username, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+	}
+
+	m := &SMTPMailer{Addr: addr, From: from}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+// Send doesn't honour ctx beyond checking it up front, net/smtp has no way
+// to cancel a send in progress.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := format(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(m.Addr, m.auth, m.From, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("error sending mail: %w", err)
+	}
+	return nil
+}
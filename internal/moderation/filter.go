@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const mask = "****"
+
+// Filter masks banned words in chirp bodies. It is safe for concurrent use
+// and its word list can be swapped at any time with Replace.
+type Filter struct {
+	mu    sync.RWMutex
+	words map[string]bool
+}
+
+func NewFilter(words []string) *Filter {
+	f := &Filter{}
+	f.Replace(words)
+	return f
+}
+
+// Replace swaps the banned word list for a new one.
+func (f *Filter) Replace(words []string) {
+	skeletons := make(map[string]bool, len(words))
+	for _, word := range words {
+		if s := Skeleton(word); s != "" {
+			skeletons[s] = true
+		}
+	}
+
+	f.mu.Lock()
+	f.words = skeletons
+	f.mu.Unlock()
+}
+
+// Clean replaces every banned word in body with ****. Punctuation around a
+// word and the whitespace between words are left as they were.
+func (f *Filter) Clean(body string) string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if len(f.words) == 0 {
+		return body
+	}
+
+	var b strings.Builder
+	start := -1
+	for i, r := range body {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				b.WriteString(f.cleanWord(body[start:i]))
+				start = -1
+			}
+			b.WriteRune(r)
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		b.WriteString(f.cleanWord(body[start:]))
+	}
+
+	return b.String()
+}
+
+func (f *Filter) cleanWord(word string) string {
+	// "fornax!" only matches without its punctuation, "$harbert" only with
+	core := strings.TrimFunc(word, isPunct)
+	if core != "" && f.words[Skeleton(core)] {
+		prefix := word[:strings.Index(word, core)]
+		suffix := word[len(prefix)+len(core):]
+		return prefix + mask + suffix
+	}
+
+	if f.words[Skeleton(word)] {
+		return mask
+	}
+
+	return word
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// Skeleton reduces a word to the lowercase ASCII letters it looks like, so
+// "K3rfuƒƒle", "ｋｅｒｆｕｆｆｌｅ" and "k.e.r.f.u.f.f.l.e" all compare equal.
+// Letters that are easy to swap for each other collapse to one. Digits only
+// stand in for letters in words that have letters too, so "455" stays a
+// number.
+func Skeleton(word string) string {
+	foldDigits := strings.IndexFunc(word, unicode.IsLetter) >= 0
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			// fullwidth forms mirror ASCII
+			r = unicode.ToLower(r - 0xFEE0)
+		}
+		if unicode.IsDigit(r) && !foldDigits {
+			continue
+		}
+		if mapped, ok := lookalikes[r]; ok {
+			r = mapped
+		}
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var lookalikes = map[rune]rune{
+	// leetspeak
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '!': 'i', '|': 'i', '+': 't', '€': 'e', '£': 'i',
+	// letters that are hard to tell apart. Everything that looks like an l
+	// has to map to i as well, since no skeleton has an l left in it.
+	'l': 'i',
+	// accented latin
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ā': 'a',
+	'ç': 'c', 'ć': 'c', 'č': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ē': 'e', 'ę': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i', 'ı': 'i',
+	'ñ': 'n', 'ń': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o', 'ō': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ū': 'u',
+	'ý': 'y', 'ÿ': 'y',
+	'ś': 's', 'š': 's', 'ž': 'z', 'ź': 'z', 'ż': 'z', 'ƒ': 'f',
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ј': 'j', 'ԁ': 'd',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
@@ -0,0 +1,50 @@
+package moderation
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	f := NewFilter([]string{"kerfuffle", "sharbert", "fornax"})
+
+	cases := map[string]string{
+		"This is a kerfuffle opinion I need to share with the world": "This is a **** opinion I need to share with the world",
+		"I had something interesting for breakfast":                  "I had something interesting for breakfast",
+		"Sharbert! Fornax, KERFUFFLE.":                               "****! ****, ****.",
+		"k3rfuff1e and $h@rbert":                                     "**** and ****",
+		"ｆｏｒｎａｘ or fоrnах":                                           "**** or ****",
+		"f.o.r.n.a.x":                                                "****",
+		"keep  the\nspacing":                                         "keep  the\nspacing",
+		"fornaxes are fine":                                          "fornaxes are fine",
+		"k3rfuff£e":                                                  "****",
+	}
+
+	for body, want := range cases {
+		if got := f.Clean(body); got != want {
+			t.Errorf("Clean(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	f := NewFilter([]string{"fornax"})
+	f.Replace([]string{"sharbert"})
+
+	if got := f.Clean("fornax sharbert"); got != "fornax ****" {
+		t.Errorf("expected replaced word list to apply, got %q", got)
+	}
+}
+
+func TestCleanLeavesNumbers(t *testing.T) {
+	f := NewFilter([]string{"ass", "bot"})
+
+	cases := map[string]string{
+		"I owe you 455 dollars": "I owe you 455 dollars",
+		"call 807!":             "call 807!",
+		"a55 and b0t":           "**** and ****",
+	}
+
+	for body, want := range cases {
+		if got := f.Clean(body); got != want {
+			t.Errorf("Clean(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/realquiller/chirpy_server/internal/auth"
 	"github.com/realquiller/chirpy_server/internal/database"
 	"github.com/realquiller/chirpy_server/internal/handlers"
+	"github.com/realquiller/chirpy_server/internal/mailer"
+)
+
+const (
+	subscriptionSweepInterval = 10 * time.Minute
+	scheduledChirpInterval    = 30 * time.Second
+	webhookDispatchInterval   = 15 * time.Second
+	bannedWordsReloadInterval = time.Minute
 )
 
 func main() {
@@ -30,9 +43,72 @@ func main() {
 	apiCfg.Platform = os.Getenv("PLATFORM")
 
 	apiCfg.DbQueries = dbQueries
+	apiCfg.Db = db
 
-	apiCfg.Secret = os.Getenv("SECRET")
-	apiCfg.PolkaKey = os.Getenv("POLKA_KEY")
+	// JWT_SIGNING_KEYS and JWT_RETIRING_KEYS are comma separated PEM files.
+	// Without signing keys tokens are signed with SECRET using HS256.
+	keys, err := auth.LoadKeySet(
+		splitList(os.Getenv("JWT_SIGNING_KEYS")),
+		splitList(os.Getenv("JWT_RETIRING_KEYS")),
+		os.Getenv("SECRET"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	apiCfg.Keys = keys
+
+	// POLKA_WEBHOOK_SECRETS is comma separated, the old and new secret both
+	// go in while rotating. POLKA_KEY is the single secret it replaces.
+	apiCfg.PolkaSecrets = splitList(os.Getenv("POLKA_WEBHOOK_SECRETS"))
+	if len(apiCfg.PolkaSecrets) == 0 && os.Getenv("POLKA_KEY") != "" {
+		apiCfg.PolkaSecrets = []string{os.Getenv("POLKA_KEY")}
+	}
+
+	apiCfg.RedGracePeriod = handlers.DefaultRedGracePeriod
+	if v := os.Getenv("CHIRPY_RED_GRACE_PERIOD"); v != "" {
+		grace, err := time.ParseDuration(v)
+		if err != nil || grace < 0 {
+			log.Fatalf("Invalid CHIRPY_RED_GRACE_PERIOD: %q", v)
+		}
+		apiCfg.RedGracePeriod = grace
+	}
+
+	if err := auth.SetPasswordParams(argon2Params()); err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+
+	// SMTP_ADDR switches on real mail, otherwise it's written to MAIL_DIR
+	mailFrom := envOr("MAIL_FROM", "Chirpy <no-reply@chirpy.local>")
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		smtpMailer, err := mailer.NewSMTPMailer(addr, mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+		if err != nil {
+			log.Fatalf("Failed to set up mailer: %v", err)
+		}
+		apiCfg.Mailer = smtpMailer
+	} else {
+		apiCfg.Mailer = mailer.NewFileMailer(envOr("MAIL_DIR", "mail"), mailFrom)
+	}
+	apiCfg.BaseURL = strings.TrimSuffix(envOr("APP_URL", "http://localhost:8080"), "/")
+	apiCfg.RequireEmailVerification = os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+
+	if err := apiCfg.ReloadBannedWords(context.Background()); err != nil {
+		log.Fatalf("Failed to load banned words: %v", err)
+	}
+
+	// picks up banned words changed through other instances
+	go apiCfg.RunBannedWordsReloader(context.Background(), bannedWordsReloadInterval)
+
+	// expires lapsed Chirpy Red subscriptions
+	go apiCfg.RunSubscriptionSweeper(context.Background(), subscriptionSweepInterval)
+
+	// publishes scheduled chirps once they're due
+	go apiCfg.RunScheduledChirpPublisher(context.Background(), scheduledChirpInterval)
+
+	// sends and retries outbound webhooks
+	go apiCfg.RunWebhookDispatcher(context.Background(), webhookDispatchInterval)
+
+	// JWKS handler
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.JWKSHandler)
 
 	// Health check
 	mux.HandleFunc("GET /api/healthz", handlers.ReadinessHandler)
@@ -48,6 +124,19 @@ func main() {
 	// Reset handler
 	mux.HandleFunc("POST /admin/reset", apiCfg.ResetHandler)
 
+	// Admin user handlers
+	mux.Handle("POST /admin/users/{userid}/unlock", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.UnlockUserHandler)))
+
+	// Webhook event handlers
+	mux.Handle("GET /admin/webhook-events/failed", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.ListFailedWebhookEventsHandler)))
+	mux.Handle("POST /admin/webhook-events/replay", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.ReplayFailedWebhookEventsHandler)))
+	mux.Handle("POST /admin/webhook-events/{id}/replay", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.ReplayWebhookEventHandler)))
+
+	// Banned word handlers
+	mux.Handle("GET /admin/banned-words", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.ListBannedWordsHandler)))
+	mux.Handle("POST /admin/banned-words", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.CreateBannedWordHandler)))
+	mux.Handle("DELETE /admin/banned-words/{word}", apiCfg.MiddlewareAdmin(http.HandlerFunc(apiCfg.DeleteBannedWordHandler)))
+
 	// NewUser handler
 	mux.HandleFunc("POST /api/users", apiCfg.NewUserHandler)
 
@@ -57,6 +146,9 @@ func main() {
 	// GetChirps handler
 	mux.HandleFunc("GET /api/chirps", apiCfg.GetChirpsHandler)
 
+	// SearchChirps handler
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.SearchChirpsHandler)
+
 	// GetChirp handler
 	mux.HandleFunc("GET /api/chirps/{chirpid}", apiCfg.GetChirpHandler)
 
@@ -69,15 +161,84 @@ func main() {
 	// Revoke handler
 	mux.HandleFunc("POST /api/revoke", apiCfg.RevokeHandler)
 
+	// Email verification handlers
+	mux.HandleFunc("POST /api/email-verification/confirm", apiCfg.ConfirmEmailHandler)
+	mux.HandleFunc("POST /api/email-verification/resend", apiCfg.ResendVerificationHandler)
+	mux.HandleFunc("GET /verify-email", apiCfg.VerifyEmailPageHandler)
+
+	// Password reset handlers
+	mux.HandleFunc("POST /api/password-reset/request", apiCfg.PasswordResetRequestHandler)
+	mux.HandleFunc("POST /api/password-reset/confirm", apiCfg.PasswordResetConfirmHandler)
+	mux.HandleFunc("GET /reset-password", apiCfg.PasswordResetPageHandler)
+
+	// Two-factor handlers
+	mux.HandleFunc("POST /api/2fa/enroll", apiCfg.EnrollTOTPHandler)
+	mux.HandleFunc("POST /api/2fa/verify", apiCfg.VerifyTOTPHandler)
+	mux.HandleFunc("POST /api/2fa/disable", apiCfg.DisableTOTPHandler)
+	mux.HandleFunc("POST /api/login/2fa", apiCfg.Login2FAHandler)
+
+	// Personal access token handlers
+	mux.HandleFunc("POST /api/tokens", apiCfg.CreateTokenHandler)
+	mux.HandleFunc("GET /api/tokens", apiCfg.ListTokensHandler)
+	mux.HandleFunc("DELETE /api/tokens/{id}", apiCfg.RevokeTokenHandler)
+
+	// Session handlers
+	mux.HandleFunc("GET /api/sessions", apiCfg.GetSessionsHandler)
+	mux.HandleFunc("DELETE /api/sessions/{id}", apiCfg.RevokeSessionHandler)
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.RevokeAllSessionsHandler)
+
+	// PatchMe handler
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.PatchMeHandler)
+
 	// UpdateUser handler
 	mux.HandleFunc("PUT /api/users", apiCfg.UpdateUserHandler)
 
+	// EditChirp handler, Chirpy Red only
+	mux.Handle("PATCH /api/chirps/{chirpid}", apiCfg.MiddlewareRequireFeature(handlers.FeatureChirpEditing, http.HandlerFunc(apiCfg.EditChirpHandler)))
+
+	// Scheduled chirp handlers, scheduling is Chirpy Red only
+	mux.Handle("POST /api/scheduled-chirps", apiCfg.MiddlewareRequireFeature(handlers.FeatureScheduledChirps, http.HandlerFunc(apiCfg.CreateScheduledChirpHandler)))
+	mux.HandleFunc("GET /api/scheduled-chirps", apiCfg.ListScheduledChirpsHandler)
+	mux.HandleFunc("DELETE /api/scheduled-chirps/{id}", apiCfg.DeleteScheduledChirpHandler)
+
 	// DeleteChirp handler
 	mux.HandleFunc("DELETE /api/chirps/{chirpid}", apiCfg.DeleteChirpHandler)
 
 	// WebhookUpgradeUser handler
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.WebhookUpgradeUserHandler)
 
+	// Outbound webhook handlers
+	mux.HandleFunc("POST /api/webhooks", apiCfg.CreateWebhookSubscriptionHandler)
+	mux.HandleFunc("GET /api/webhooks", apiCfg.ListWebhookSubscriptionsHandler)
+	mux.HandleFunc("DELETE /api/webhooks/{id}", apiCfg.DeleteWebhookSubscriptionHandler)
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", apiCfg.ListWebhookDeliveriesHandler)
+
+	// Profile handler
+	mux.HandleFunc("GET /api/users/{username}", apiCfg.GetProfileHandler)
+
+	// Follow handlers
+	mux.HandleFunc("POST /api/users/{userid}/follow", apiCfg.FollowHandler)
+	mux.HandleFunc("DELETE /api/users/{userid}/follow", apiCfg.UnfollowHandler)
+	mux.HandleFunc("GET /api/users/{userid}/followers", apiCfg.GetFollowersHandler)
+	mux.HandleFunc("GET /api/users/{userid}/following", apiCfg.GetFollowingHandler)
+
+	// Thread handler
+	mux.HandleFunc("GET /api/chirps/{chirpid}/thread", apiCfg.GetThreadHandler)
+
+	// Like handlers
+	mux.HandleFunc("POST /api/chirps/{chirpid}/likes", apiCfg.LikeChirpHandler)
+	mux.HandleFunc("DELETE /api/chirps/{chirpid}/likes", apiCfg.UnlikeChirpHandler)
+
+	// Tag handlers
+	mux.HandleFunc("GET /api/tags/trending", apiCfg.TrendingTagsHandler)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", apiCfg.GetTagChirpsHandler)
+
+	// Mentions handler
+	mux.HandleFunc("GET /api/users/me/mentions", apiCfg.GetMyMentionsHandler)
+
+	// Timeline handler
+	mux.HandleFunc("GET /api/timeline", apiCfg.TimelineHandler)
+
 	server := &http.Server{
 		Handler: mux,
 		Addr:    ":8080",
@@ -86,6 +247,49 @@ func main() {
 	log.Fatal(server.ListenAndServe())
 }
 
+// argon2Params reads ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and
+// ARGON2_PARALLELISM, keeping the defaults for any that aren't set.
+func argon2Params() auth.Argon2Params {
+	p := auth.DefaultArgon2Params
+	for key, field := range map[string]*uint32{
+		"ARGON2_MEMORY_KIB": &p.Memory,
+		"ARGON2_ITERATIONS": &p.Iterations,
+	} {
+		if v := os.Getenv(key); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				log.Fatalf("Invalid %s: %v", key, err)
+			}
+			*field = uint32(n)
+		}
+	}
+	if v := os.Getenv("ARGON2_PARALLELISM"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			log.Fatalf("Invalid ARGON2_PARALLELISM: %v", err)
+		}
+		p.Parallelism = uint8(n)
+	}
+	return p
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // go build -o out && ./out
 
 // postgres: sudo -u postgres psql
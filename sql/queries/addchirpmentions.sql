@@ -0,0 +1,8 @@
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset, created_at)
+SELECT sqlc.arg('chirp_id')::uuid,
+       UNNEST(sqlc.arg('user_ids')::uuid[]),
+       UNNEST(sqlc.arg('start_offsets')::int[]),
+       UNNEST(sqlc.arg('end_offsets')::int[]),
+       NOW()
+ON CONFLICT DO NOTHING;
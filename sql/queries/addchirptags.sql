@@ -0,0 +1,4 @@
+-- name: AddChirpTags :exec
+INSERT INTO chirp_tags (chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, UNNEST(sqlc.arg('tags')::text[]), NOW()
+ON CONFLICT DO NOTHING;
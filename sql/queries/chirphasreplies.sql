@@ -0,0 +1,4 @@
+-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE chirps.in_reply_to = $1
+) AS has_replies;
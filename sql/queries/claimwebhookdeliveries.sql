@@ -0,0 +1,13 @@
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + make_interval(secs => sqlc.arg('lease_seconds')::float8), updated_at = NOW()
+FROM webhook_subscriptions
+WHERE webhook_subscriptions.id = webhook_deliveries.subscription_id
+  AND webhook_deliveries.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at ASC
+    LIMIT sqlc.arg('row_limit')
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING webhook_deliveries.*, webhook_subscriptions.url, webhook_subscriptions.secret;
\ No newline at end of file
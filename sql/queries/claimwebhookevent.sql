@@ -0,0 +1,10 @@
+-- name: ClaimWebhookEvent :one
+INSERT INTO webhook_events (event_id, event_type, payload)
+VALUES (sqlc.arg('event_id'), sqlc.arg('event_type'), sqlc.arg('payload'))
+ON CONFLICT (event_id) DO UPDATE
+SET status = 'processing', error = NULL, attempts = webhook_events.attempts + 1, updated_at = NOW()
+WHERE webhook_events.status = 'failed'
+   -- whoever was processing it gave up without recording an outcome
+   OR (webhook_events.status = 'processing'
+       AND webhook_events.updated_at < NOW() - make_interval(secs => sqlc.arg('lease_seconds')::float8))
+RETURNING *;
\ No newline at end of file
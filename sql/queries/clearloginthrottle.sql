@@ -0,0 +1,3 @@
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
@@ -0,0 +1,9 @@
+-- name: CreateBannedWord :one
+INSERT INTO banned_words (word, created_at, created_by)
+VALUES (
+    $1,
+    NOW(),
+    $2
+)
+ON CONFLICT (word) DO NOTHING
+RETURNING *;
@@ -1,10 +1,11 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to)
 VALUES (
     gen_random_uuid(),
     NOW(), 
     NOW(), 
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
\ No newline at end of file
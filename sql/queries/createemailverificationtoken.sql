@@ -0,0 +1,3 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at)
+VALUES ($1, $2, $3, $4);
@@ -0,0 +1,3 @@
+-- name: CreateRecoveryCodes :exec
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT sqlc.arg('user_id')::uuid, UNNEST(sqlc.arg('code_hashes')::text[]);
@@ -1,11 +1,16 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip_address, last_used_at)
 VALUES (
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6,
+    $7,
+    NOW()
 )
 RETURNING *;
\ No newline at end of file
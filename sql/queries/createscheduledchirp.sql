@@ -0,0 +1,4 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (user_id, body, publish_at)
+VALUES ($1, $2, $3)
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- name: CreateSecurityEvent :exec
+INSERT INTO security_events (id, created_at, user_id, event_type, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3
+);
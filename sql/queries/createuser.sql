@@ -1,10 +1,13 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, username, display_name, bio)
 VALUES (
     gen_random_uuid(),
     NOW(), 
     NOW(), 
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (user_id, url, events, secret)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
\ No newline at end of file
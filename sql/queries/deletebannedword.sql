@@ -0,0 +1,3 @@
+-- name: DeleteBannedWord :execrows
+DELETE FROM banned_words
+WHERE banned_words.word = $1;
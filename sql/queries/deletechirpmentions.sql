@@ -0,0 +1,3 @@
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
\ No newline at end of file
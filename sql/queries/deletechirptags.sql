@@ -0,0 +1,3 @@
+-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = $1;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- name: DeleteChirpTagsExcept :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = sqlc.arg('chirp_id') AND NOT (tag = ANY(sqlc.arg('tags')::text[]));
\ No newline at end of file
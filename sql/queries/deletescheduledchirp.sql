@@ -0,0 +1,3 @@
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2 AND status = 'pending';
\ No newline at end of file
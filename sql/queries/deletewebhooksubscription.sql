@@ -0,0 +1,3 @@
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1 AND user_id = $2;
\ No newline at end of file
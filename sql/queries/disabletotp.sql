@@ -0,0 +1,4 @@
+-- name: DisableTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,4 @@
+-- name: EnableTOTP :exec
+UPDATE users
+SET totp_enabled = TRUE, updated_at = NOW()
+WHERE id = $1 AND totp_secret IS NOT NULL;
@@ -0,0 +1,7 @@
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+SELECT webhook_subscriptions.id, sqlc.arg('event_id'), sqlc.arg('event_type'), sqlc.arg('payload')
+FROM webhook_subscriptions
+JOIN users ON users.id = webhook_subscriptions.user_id
+WHERE sqlc.arg('event_type')::text = ANY(webhook_subscriptions.events)
+  AND (users.is_admin OR webhook_subscriptions.user_id = sqlc.arg('subject_user_id'));
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: ExpirePasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
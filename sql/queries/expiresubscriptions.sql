@@ -0,0 +1,13 @@
+-- name: ExpireSubscriptions :many
+WITH expired AS (
+    UPDATE subscriptions
+    SET status = 'expired', updated_at = NOW()
+    WHERE (status = 'past_due' AND current_period_end < NOW() - make_interval(secs => sqlc.arg('grace_seconds')::float8))
+       OR (status = 'canceled' AND current_period_end < NOW())
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = false, updated_at = NOW()
+FROM expired
+WHERE users.id = expired.user_id
+RETURNING users.id;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: FailScheduledChirp :exec
+UPDATE scheduled_chirps
+SET status = 'failed', error = $2, updated_at = NOW()
+WHERE id = $1 AND status = 'pending';
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- name: FinishWebhookEvent :one
+UPDATE webhook_events
+SET status = $2,
+    error = $3,
+    processed_at = CASE WHEN $2 = 'failed' THEN NULL ELSE NOW() END,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
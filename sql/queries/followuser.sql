@@ -0,0 +1,8 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
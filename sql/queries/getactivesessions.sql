@@ -0,0 +1,9 @@
+-- name: GetActiveSessions :many
+SELECT refresh_tokens.token, refresh_tokens.family_id, refresh_tokens.user_agent, refresh_tokens.ip_address, refresh_tokens.last_used_at,
+       (SELECT MIN(family.created_at) FROM refresh_tokens AS family WHERE family.family_id = refresh_tokens.family_id)::timestamp AS session_created_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.rotated_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
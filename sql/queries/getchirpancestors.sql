@@ -0,0 +1,14 @@
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.in_reply_to, parent.deleted_at, parent.edited_at, 1 AS depth
+    FROM chirps AS child
+    JOIN chirps AS parent ON parent.id = child.in_reply_to
+    WHERE child.id = $1
+    UNION ALL
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.in_reply_to, parent.deleted_at, parent.edited_at, ancestors.depth + 1
+    FROM ancestors
+    JOIN chirps AS parent ON parent.id = ancestors.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at
+FROM ancestors
+ORDER BY depth DESC;
@@ -0,0 +1,7 @@
+-- name: GetChirpLikeStats :many
+SELECT chirp_likes.chirp_id,
+       COUNT(*)::bigint AS like_count,
+       COALESCE(BOOL_OR(chirp_likes.user_id = sqlc.narg('viewer_id')::uuid), false)::boolean AS liked_by_me
+FROM chirp_likes
+WHERE chirp_likes.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+GROUP BY chirp_likes.chirp_id;
@@ -0,0 +1,6 @@
+-- name: GetChirpMentions :many
+SELECT chirp_mentions.chirp_id, chirp_mentions.user_id, users.username, chirp_mentions.start_offset, chirp_mentions.end_offset
+FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_mentions.chirp_id, chirp_mentions.start_offset;
@@ -0,0 +1,15 @@
+-- name: GetChirpReplies :many
+WITH RECURSIVE replies AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, 1 AS depth
+    FROM chirps
+    WHERE chirps.in_reply_to = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at, replies.depth + 1
+    FROM chirps
+    JOIN replies ON chirps.in_reply_to = replies.id
+    WHERE replies.depth < sqlc.arg('max_depth')::int
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, edited_at, depth
+FROM replies
+ORDER BY depth ASC, created_at ASC, id ASC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,9 @@
+-- name: GetChirpsAfter :many
+SELECT chirps.*
+FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
@@ -1,4 +0,0 @@
--- name: GetChirpsByAuthor :many
-SELECT chirps.* FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC;
@@ -0,0 +1,10 @@
+-- name: GetChirpsByTag :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.tag = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
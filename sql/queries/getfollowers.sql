@@ -0,0 +1,8 @@
+-- name: GetFollowers :many
+SELECT follows.follower_id AS user_id, follows.created_at
+FROM follows
+WHERE follows.followee_id = sqlc.arg('user_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (follows.created_at, follows.follower_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY follows.created_at DESC, follows.follower_id DESC
+LIMIT sqlc.arg('row_limit');
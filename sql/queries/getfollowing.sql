@@ -0,0 +1,8 @@
+-- name: GetFollowing :many
+SELECT follows.followee_id AS user_id, follows.created_at
+FROM follows
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (follows.created_at, follows.followee_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY follows.created_at DESC, follows.followee_id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,4 @@
+-- name: GetLoginLocks :many
+SELECT key, locked_until
+FROM login_throttles
+WHERE key = ANY(sqlc.arg('keys')::text[]) AND locked_until > NOW();
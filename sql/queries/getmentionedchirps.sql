@@ -0,0 +1,12 @@
+-- name: GetMentionedChirps :many
+SELECT chirps.*
+FROM chirps
+WHERE EXISTS (
+    SELECT 1 FROM chirp_mentions
+    WHERE chirp_mentions.chirp_id = chirps.id AND chirp_mentions.user_id = sqlc.arg('user_id')
+  )
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
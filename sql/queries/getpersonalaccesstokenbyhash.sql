@@ -0,0 +1,6 @@
+-- name: GetPersonalAccessTokenByHash :one
+SELECT personal_access_tokens.*
+FROM personal_access_tokens
+WHERE token_hash = $1
+    AND revoked_at IS NULL
+    AND (expires_at IS NULL OR expires_at > NOW());
@@ -0,0 +1,3 @@
+-- name: GetSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- name: GetTimeline :many
+SELECT chirps.*
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,11 @@
+-- name: GetTrendingTags :many
+SELECT chirp_tags.tag,
+       COUNT(*)::bigint AS uses,
+       SUM(POWER(0.5, EXTRACT(EPOCH FROM (NOW() - chirp_tags.created_at)) / sqlc.arg('half_life_seconds')::float8))::float8 AS score
+FROM chirp_tags
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at > NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8)
+  AND chirps.deleted_at IS NULL
+GROUP BY chirp_tags.tag
+ORDER BY score DESC, uses DESC, chirp_tags.tag ASC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,4 @@
+-- name: GetUserByID :one
+SELECT users.*
+FROM users
+WHERE users.id = $1;
@@ -0,0 +1,7 @@
+-- name: GetUserProfile :one
+SELECT users.id, users.created_at, users.username, users.display_name, users.bio, users.is_chirpy_red,
+       (SELECT COUNT(*) FROM follows WHERE follows.followee_id = users.id) AS follower_count,
+       (SELECT COUNT(*) FROM follows WHERE follows.follower_id = users.id) AS following_count,
+       (SELECT COUNT(*) FROM chirps WHERE chirps.user_id = users.id AND chirps.deleted_at IS NULL) AS chirp_count
+FROM users
+WHERE LOWER(users.username) = LOWER(sqlc.arg('username')::text);
@@ -0,0 +1,4 @@
+-- name: GetUsersByUsernames :many
+SELECT users.id, users.username
+FROM users
+WHERE LOWER(users.username) = ANY(sqlc.arg('usernames')::text[]);
@@ -0,0 +1,4 @@
+-- name: GetWebhookEventStatus :one
+SELECT status
+FROM webhook_events
+WHERE event_id = $1;
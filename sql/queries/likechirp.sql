@@ -0,0 +1,8 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- name: ListBannedWords :many
+SELECT banned_words.*
+FROM banned_words
+ORDER BY banned_words.word ASC;
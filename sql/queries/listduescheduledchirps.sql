@@ -0,0 +1,6 @@
+-- name: ListDueScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE status = 'pending' AND publish_at <= NOW()
+    AND (retry_at IS NULL OR retry_at <= NOW())
+ORDER BY publish_at ASC, id ASC
+LIMIT $1;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- name: ListFailedWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE status = 'failed'
+   OR (status = 'processing' AND updated_at < NOW() - make_interval(secs => sqlc.arg('lease_seconds')::float8))
+ORDER BY created_at ASC, id ASC;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- name: ListPersonalAccessTokens :many
+SELECT personal_access_tokens.*
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
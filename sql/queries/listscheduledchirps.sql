@@ -0,0 +1,4 @@
+-- name: ListScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = $1
+ORDER BY publish_at ASC, id ASC;
\ No newline at end of file
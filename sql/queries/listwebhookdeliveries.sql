@@ -0,0 +1,5 @@
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: LockLoginThrottle :exec
+UPDATE login_throttles
+SET locked_until = $2
+WHERE key = $1;
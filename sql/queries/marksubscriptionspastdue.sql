@@ -0,0 +1,4 @@
+-- name: MarkSubscriptionsPastDue :execrows
+UPDATE subscriptions
+SET status = 'past_due', updated_at = NOW()
+WHERE status = 'active' AND current_period_end < NOW();
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: PublishScheduledChirp :execrows
+UPDATE scheduled_chirps
+SET status = 'published', chirp_id = $2, updated_at = NOW()
+WHERE id = $1 AND status = 'pending';
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES (sqlc.arg('key'), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+        WHEN login_throttles.last_failure_at < NOW() - make_interval(secs => sqlc.arg('window_seconds')::float8) THEN 1
+        ELSE login_throttles.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING failures;
@@ -0,0 +1,10 @@
+-- name: RecordWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = attempts + 1,
+    last_attempt_at = NOW(),
+    next_attempt_at = $3,
+    response_status = $4,
+    error = $5,
+    updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- name: RetryScheduledChirp :exec
+UPDATE scheduled_chirps
+SET attempts = attempts + 1,
+    error = sqlc.arg('error'),
+    retry_at = NOW() + make_interval(secs => sqlc.arg('retry_after_seconds')::float8),
+    status = CASE WHEN attempts + 1 >= sqlc.arg('max_attempts')::int THEN 'failed' ELSE status END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND status = 'pending';
\ No newline at end of file
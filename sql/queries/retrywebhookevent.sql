@@ -0,0 +1,7 @@
+-- name: RetryWebhookEvent :one
+UPDATE webhook_events
+SET status = 'processing', error = NULL, attempts = attempts + 1, updated_at = NOW()
+WHERE id = sqlc.arg('id')
+  AND (status = 'failed'
+       OR (status = 'processing' AND updated_at < NOW() - make_interval(secs => sqlc.arg('lease_seconds')::float8)))
+RETURNING *;
\ No newline at end of file
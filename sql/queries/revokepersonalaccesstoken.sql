@@ -0,0 +1,4 @@
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
@@ -0,0 +1,4 @@
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
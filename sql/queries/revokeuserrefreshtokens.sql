@@ -0,0 +1,4 @@
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,4 @@
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET rotated_at = NOW(), updated_at = NOW()
+WHERE token = $1 AND rotated_at IS NULL AND revoked_at IS NULL;
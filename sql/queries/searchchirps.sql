@@ -0,0 +1,12 @@
+-- name: SearchChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.edited_at,
+       ts_rank(to_tsvector('english', chirps.body), tsq)::real AS rank,
+       ts_headline('english', translate(chirps.body, chr(2) || chr(3), ''), tsq, 'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2')::text AS snippet
+FROM chirps, websearch_to_tsquery('english', sqlc.arg('query')::text) AS tsq
+WHERE to_tsvector('english', chirps.body) @@ tsq
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_rank')::real IS NULL
+       OR (ts_rank(to_tsvector('english', chirps.body), tsq), chirps.created_at, chirps.id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
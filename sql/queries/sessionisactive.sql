@@ -0,0 +1,9 @@
+-- name: SessionIsActive :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = $1
+      AND refresh_tokens.user_id = $2
+      AND refresh_tokens.revoked_at IS NULL
+      AND refresh_tokens.rotated_at IS NULL
+      AND refresh_tokens.expires_at > NOW()
+) AS active;
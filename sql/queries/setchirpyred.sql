@@ -0,0 +1,4 @@
+-- name: SetChirpyRed :execrows
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1;
\ No newline at end of file
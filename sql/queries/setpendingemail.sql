@@ -0,0 +1,4 @@
+-- name: SetPendingEmail :exec
+UPDATE users
+SET pending_email = $2, updated_at = NOW()
+WHERE users.id = $1;
@@ -0,0 +1,5 @@
+-- name: SetSubscriptionStatus :one
+UPDATE subscriptions
+SET status = $2, updated_at = NOW()
+WHERE user_id = $1
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- name: SetTOTPSecret :exec
+UPDATE users
+SET totp_secret = sqlc.arg('totp_secret'), totp_enabled = FALSE, totp_last_step = 0, updated_at = NOW()
+WHERE id = sqlc.arg('id');
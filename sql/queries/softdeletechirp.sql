@@ -0,0 +1,4 @@
+-- name: SoftDeleteChirp :exec
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE chirps.id = $1;
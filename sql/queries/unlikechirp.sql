@@ -0,0 +1,3 @@
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE chirp_id = $1 AND user_id = $2;
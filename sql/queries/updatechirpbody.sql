@@ -0,0 +1,5 @@
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, edited_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
\ No newline at end of file
@@ -1,4 +0,0 @@
--- name: UpdateUser :exec
-UPDATE users
-SET hashed_password = $2, email = $3, updated_at = NOW()
-WHERE users.id = $1;
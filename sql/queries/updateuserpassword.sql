@@ -0,0 +1,4 @@
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE users.id = $1;
@@ -0,0 +1,8 @@
+-- name: UpdateUserProfile :one
+UPDATE users
+SET username = COALESCE(sqlc.narg('username'), username),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    updated_at = NOW()
+WHERE users.id = sqlc.arg('id')
+RETURNING *;
@@ -0,0 +1,9 @@
+-- name: UpsertSubscription :one
+INSERT INTO subscriptions (user_id, plan, status, current_period_end)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = EXCLUDED.status,
+    current_period_end = EXCLUDED.current_period_end,
+    updated_at = NOW()
+RETURNING *;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, email;
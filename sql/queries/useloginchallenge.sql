@@ -0,0 +1,8 @@
+-- name: UseLoginChallenge :execrows
+WITH pruned AS (
+    DELETE FROM used_login_challenges
+    WHERE used_login_challenges.expires_at < NOW()
+)
+INSERT INTO used_login_challenges (id, expires_at)
+VALUES (sqlc.arg('id'), sqlc.arg('expires_at'))
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,4 @@
+-- name: UseTOTPStep :execrows
+UPDATE users
+SET totp_last_step = sqlc.arg('step')
+WHERE id = sqlc.arg('id') AND totp_last_step < sqlc.arg('step');
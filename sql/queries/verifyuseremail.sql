@@ -0,0 +1,9 @@
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email = sqlc.arg('email'),
+    email_verified = TRUE,
+    pending_email = NULL,
+    updated_at = NOW()
+WHERE users.id = sqlc.arg('id')
+    AND (users.email = sqlc.arg('email') OR users.pending_email = sqlc.arg('email'))
+RETURNING *;
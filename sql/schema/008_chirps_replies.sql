@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN in_reply_to UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to);
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN deleted_at,
+DROP COLUMN in_reply_to;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN username TEXT;
+
+CREATE UNIQUE INDEX users_username_lower_idx ON users (LOWER(username));
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN username;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE banned_words(
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    created_by UUID,
+    FOREIGN KEY (created_by)
+        REFERENCES users(id)
+        ON DELETE SET NULL
+);
+
+INSERT INTO banned_words (word, created_at)
+VALUES ('kerfuffle', NOW()), ('sharbert', NOW()), ('fornax', NOW());
+
+-- +goose Down
+DROP TABLE banned_words;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id UUID NOT NULL DEFAULT gen_random_uuid(),
+ADD COLUMN parent_token TEXT REFERENCES refresh_tokens(token) ON DELETE SET NULL,
+ADD COLUMN rotated_at TIMESTAMP;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+CREATE TABLE security_events(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    details TEXT NOT NULL,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE security_events;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN rotated_at,
+DROP COLUMN parent_token,
+DROP COLUMN family_id;
\ No newline at end of file
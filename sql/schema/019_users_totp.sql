@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    used_at TIMESTAMP,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX recovery_codes_user_code_idx ON recovery_codes (user_id, code_hash);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_last_step,
+DROP COLUMN totp_enabled,
+DROP COLUMN totp_secret;
\ No newline at end of file
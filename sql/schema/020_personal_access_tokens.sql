@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE personal_access_tokens(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    token_hint TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX personal_access_tokens_user_id_idx ON personal_access_tokens (user_id);
+
+-- +goose Down
+DROP TABLE personal_access_tokens;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE password_reset_tokens(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
\ No newline at end of file
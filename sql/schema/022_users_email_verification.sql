@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN pending_email TEXT;
+
+-- accounts from before verification existed aren't made to verify now
+UPDATE users SET email_verified = TRUE;
+
+CREATE TABLE email_verification_tokens(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN pending_email,
+DROP COLUMN email_verified;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE webhook_events(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    event_id TEXT NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'processing',
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX webhook_events_status_idx ON webhook_events (status, created_at);
+
+-- +goose Down
+DROP TABLE webhook_events;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_end TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscriptions_status_period_end_idx ON subscriptions (status, current_period_end);
+
+-- members from before subscriptions were tracked get one 30-day period from
+-- now. Polka has no record of when they last paid us, so this is a policy
+-- call: anyone it doesn't renew within that period (and the grace period)
+-- loses Chirpy Red like any lapsed member.
+INSERT INTO subscriptions (user_id, plan, status, current_period_end)
+SELECT id, 'red', 'active', NOW() + INTERVAL '30 days'
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
\ No newline at end of file
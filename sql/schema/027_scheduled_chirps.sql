@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE scheduled_chirps(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    publish_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    error TEXT,
+    chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL
+);
+
+CREATE INDEX scheduled_chirps_status_publish_at_idx ON scheduled_chirps (status, publish_at);
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id, publish_at);
+
+-- +goose Down
+DROP TABLE scheduled_chirps;
\ No newline at end of file
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE webhook_subscriptions(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL
+);
+
+CREATE INDEX webhook_subscriptions_user_id_idx ON webhook_subscriptions (user_id);
+
+CREATE TABLE webhook_deliveries(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id UUID NOT NULL,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_attempt_at TIMESTAMP,
+    response_status INTEGER,
+    error TEXT
+);
+
+CREATE INDEX webhook_deliveries_status_next_attempt_at_idx ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX webhook_deliveries_subscription_id_idx ON webhook_deliveries (subscription_id, created_at);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_subscriptions;
\ No newline at end of file
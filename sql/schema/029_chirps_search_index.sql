@@ -0,0 +1,15 @@
+-- +goose Up
+-- Searching uses an index on the expression instead of a stored column, so
+-- queries selecting chirps.* don't drag a tsvector along with every row.
+ALTER TABLE chirps
+DROP COLUMN search;
+
+CREATE INDEX chirps_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_search_idx;
+
+ALTER TABLE chirps
+ADD COLUMN search TSVECTOR GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+
+CREATE INDEX chirps_search_idx ON chirps USING GIN (search);
\ No newline at end of file
@@ -0,0 +1,10 @@
+-- +goose Up
+-- challenge tokens that have completed a login. A row only has to outlive
+-- the token it stands for, so expired rows are pruned as new ones come in.
+CREATE TABLE used_login_challenges(
+    id UUID PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE used_login_challenges;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+-- a scheduled chirp that errors while publishing is retried a few times
+-- before it's failed, and waits until retry_at in between so it doesn't
+-- hold up the rest
+ALTER TABLE scheduled_chirps
+ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN retry_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE scheduled_chirps
+DROP COLUMN retry_at,
+DROP COLUMN attempts;
\ No newline at end of file